@@ -13,9 +13,12 @@ const path = require('path');
 
 async function main() {
     try {
-    	//Tuan[2] Accountant[3]
+    	//Tuan[2] Accountant[3] admin[4] finance[5]
     	const userName = process.argv[2];
 	const orgName = process.argv[3];
+	// Optional Fabric CA attributes checked by the chaincode access policies
+	const role = process.argv[4];
+	const dept = process.argv[5];
 	
 	const ccpPath = path.resolve(__dirname,'..', '..', '..', 'first-network', `connection-org${orgName}.json`);
 	const ccpJSON = fs.readFileSync(ccpPath, 'utf8');
@@ -50,7 +53,14 @@ async function main() {
         const adminIdentity = gateway.getCurrentIdentity();
 
         // Register the user, enroll the user, and import the new identity into the wallet.
-        const secret = await ca.register({ enrollmentID: userName, role: 'client' }, adminIdentity);
+        const attrs = [];
+        if (role) {
+            attrs.push({ name: 'role', value: role, ecert: true });
+        }
+        if (dept) {
+            attrs.push({ name: 'dept', value: dept, ecert: true });
+        }
+        const secret = await ca.register({ enrollmentID: userName, role: 'client', attrs: attrs }, adminIdentity);
         const enrollment = await ca.enroll({ enrollmentID: userName, enrollmentSecret: secret });
         const userIdentity = X509WalletMixin.createIdentity(`Org${orgName}MSP`, enrollment.certificate, enrollment.key.toBytes());
         wallet.import(userName, userIdentity);
@@ -41,8 +41,8 @@ async function main() {
       // Get the contract from the network.
       const contract = network.getContract('multisign');
 
-      await contract.submitTransaction('submitRequest', `${requestID}`, `${clientTargetAddress}`);
-      console.log('Transaction has been submitted');
+      const result = await contract.submitTransaction('submitRequest', `${requestID}`, `${clientTargetAddress}`);
+      console.log(`Transaction has been submitted, request ID: ${result.toString()}`);
 
       // Disconnect from the gateway.
       await gateway.disconnect();
@@ -13,6 +13,7 @@ async function main() {
   
       const requestID = process.argv[4];
       const choice = process.argv[5] 
+      const comment = process.argv[6] || '';
       const userName = process.argv[2];
       const orgName = process.argv[3]; 
       const ccpPath = path.resolve(__dirname, '..', '..', '..', 'first-network',  `connection-org${orgName}.json`);    
@@ -41,7 +42,7 @@ async function main() {
       // Get the contract from the network.
       const contract = network.getContract('multisign');
 
-      await contract.submitTransaction('respondToRequest', `${requestID}`, `${choice}`);
+      await contract.submitTransaction('respondToRequest', `${requestID}`, `${choice}`, `${comment}`);
       console.log('Transaction has been submitted');
 
       // Disconnect from the gateway.
@@ -44,7 +44,10 @@ async function main() {
       // Get the contract from the network.
       const contract = network.getContract('multisign');
 
-      const result = await contract.evaluateTransaction('evaluateRequest', `${requestID}`);
+      // Without a request ID, list the requests waiting for this user's vote
+      const result = requestID
+          ? await contract.evaluateTransaction('GetRequest', `${requestID}`)
+          : await contract.evaluateTransaction('PendingForMe');
       console.log(`Transaction has been evaluated, result is: ${result.toString()}`);
 
       // Disconnect from the gateway.
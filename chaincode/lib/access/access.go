@@ -0,0 +1,255 @@
+// Package access implements attribute-based access control shared by the
+// token_erc20, multisign and database chaincodes.
+//
+// Chaincodes import it as github.com/chaincode/lib/access, the path this
+// directory has when the chaincode folder is mounted into the peer CLI's GOPATH.
+package access
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/msp"
+)
+
+// Organisations of the TrustPay network. External auditors either belong to
+// AuditorMSP or carry the role=auditor attribute in their certificate
+const (
+	StaffMSP      = "OrgStaffMSP"
+	AccountantMSP = "OrgAccountantMSP"
+	ManagerMSP    = "OrgManagerMSP"
+	AuditorMSP    = "OrgAuditorMSP"
+)
+
+// Identity describes the caller of a transaction
+type Identity struct {
+	ID     string
+	MSPID  string
+	OUs    []string
+	client cid.ClientIdentity
+}
+
+// GetIdentity extracts the caller's MSP ID, organisational units and
+// Fabric CA attributes from the transaction creator
+func GetIdentity(stub cid.ChaincodeStubInterface) (*Identity, error) {
+	client, err := cid.New(stub)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get caller identity: %s", err)
+	}
+	id, err := client.GetID()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get caller ID: %s", err)
+	}
+	mspID, err := client.GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get caller MSP ID: %s", err)
+	}
+	cert, err := client.GetX509Certificate()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get caller certificate: %s", err)
+	}
+	return &Identity{ID: id, MSPID: mspID, OUs: cert.Subject.OrganizationalUnit, client: client}, nil
+}
+
+// AccountMSPID returns the MSP ID of an account, the hex-encoded serialized
+// identity a chaincode gets from GetCreator
+func AccountMSPID(account string) (string, error) {
+	creator, err := hex.DecodeString(account)
+	if err != nil {
+		return "", errcode.Newf(errcode.InvalidArgumentCode, "Invalid account ID: %s", err)
+	}
+	var identity msp.SerializedIdentity
+	err = proto.Unmarshal(creator, &identity)
+	if err != nil || identity.Mspid == "" {
+		return "", errcode.New(errcode.InvalidArgumentCode, "Account ID is not a serialized identity")
+	}
+	return identity.Mspid, nil
+}
+
+// Attribute returns the value of a Fabric CA attribute such as role or dept
+func (id *Identity) Attribute(name string) (string, bool, error) {
+	return id.client.GetAttributeValue(name)
+}
+
+// IsAuditor reports whether the caller has the read-only auditor role
+func (id *Identity) IsAuditor() (bool, error) {
+	if id.MSPID == AuditorMSP {
+		return true, nil
+	}
+	role, found, err := id.Attribute("role")
+	if err != nil {
+		return false, fmt.Errorf("Failed to get attribute role: %s", err)
+	}
+	return found && role == "auditor", nil
+}
+
+// HasOU reports whether the caller's certificate carries the organisational unit
+func (id *Identity) HasOU(ou string) bool {
+	for _, o := range id.OUs {
+		if strings.EqualFold(o, ou) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rule grants access to callers from any of MSPIDs with any of OUs that hold
+// every attribute in Attributes. Empty fields match any caller. When Auditors
+// is set, callers with the auditor role are admitted as well, and proposals
+// approved as one of Governors requires are admitted through GovernedFunction
+type Rule struct {
+	MSPIDs     []string          `json:"mspIds,omitempty"`
+	OUs        []string          `json:"ous,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Auditors   bool              `json:"auditors,omitempty"`
+	Governors  []Governance      `json:"governors,omitempty"`
+}
+
+// String describes the rule for error messages and documentation
+func (r Rule) String() string {
+	var parts []string
+	if len(r.MSPIDs) > 0 {
+		parts = append(parts, strings.Join(r.MSPIDs, " or "))
+	}
+	if len(r.OUs) > 0 {
+		parts = append(parts, "OU "+strings.Join(r.OUs, " or "))
+	}
+	names := make([]string, 0, len(r.Attributes))
+	for name := range r.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, name+"="+r.Attributes[name])
+	}
+	description := strings.Join(parts, " with ")
+	if len(parts) == 0 {
+		description = "any caller"
+	}
+	if r.Auditors {
+		description += " or an auditor"
+	}
+	for _, g := range r.Governors {
+		description += " or " + g.String()
+	}
+	return description
+}
+
+// Allows reports whether the identity satisfies the rule
+func (r Rule) Allows(id *Identity) (bool, error) {
+	if r.Auditors {
+		auditor, err := id.IsAuditor()
+		if err != nil {
+			return false, err
+		}
+		if auditor {
+			return true, nil
+		}
+	}
+	if len(r.MSPIDs) > 0 && !contains(r.MSPIDs, id.MSPID) {
+		return false, nil
+	}
+	if len(r.OUs) > 0 {
+		matched := false
+		for _, ou := range r.OUs {
+			if id.HasOU(ou) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	for name, want := range r.Attributes {
+		value, found, err := id.Attribute(name)
+		if err != nil {
+			return false, fmt.Errorf("Failed to get attribute %s: %s", name, err)
+		}
+		if !found || value != want {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Admin is the rule for chaincode administration such as data migration
+var Admin = Rule{MSPIDs: []string{ManagerMSP}, Attributes: map[string]string{"role": "admin"}}
+
+// Audit is the rule for full-ledger export queries
+var Audit = Rule{MSPIDs: []string{ManagerMSP}, Auditors: true}
+
+// DeniedError is returned when the caller does not satisfy a function's rule
+type DeniedError struct {
+	Function string
+	Reason   string
+	MSPID    string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("Permission denied: %s %s, caller is %s", e.Function, e.Reason, e.MSPID)
+}
+
+// ErrorCode reports denials as FORBIDDEN
+func (e *DeniedError) ErrorCode() errcode.Code {
+	return errcode.ForbiddenCode
+}
+
+// CheckWrite returns a *DeniedError if the caller is a read-only auditor
+func CheckWrite(stub cid.ChaincodeStubInterface, function string) error {
+	id, err := GetIdentity(stub)
+	if err != nil {
+		return err
+	}
+	auditor, err := id.IsAuditor()
+	if err != nil {
+		return err
+	}
+	if auditor {
+		return &DeniedError{Function: function, Reason: "modifies the ledger and auditors are read-only", MSPID: id.MSPID}
+	}
+	return nil
+}
+
+// Authorize returns a *DeniedError if the caller may not invoke function.
+// A nil rule admits every member of the channel. Functions that are not
+// readOnly additionally reject auditors. Governed calls are checked by
+// AuthorizeGoverned instead
+func Authorize(stub shim.ChaincodeStubInterface, function string, rule *Rule, readOnly bool) error {
+	if !readOnly {
+		err := CheckWrite(stub, function)
+		if err != nil {
+			return err
+		}
+	}
+	if rule == nil {
+		return nil
+	}
+	id, err := GetIdentity(stub)
+	if err != nil {
+		return err
+	}
+	allowed, err := rule.Allows(id)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return &DeniedError{Function: function, Reason: "requires " + rule.String(), MSPID: id.MSPID}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
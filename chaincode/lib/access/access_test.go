@@ -0,0 +1,121 @@
+package access
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/msp"
+)
+
+func TestRuleAllows(t *testing.T) {
+	staff := &Identity{MSPID: StaffMSP, OUs: []string{"client", "Payroll"}}
+	manager := &Identity{MSPID: ManagerMSP, OUs: []string{"client"}}
+	tests := []struct {
+		name string
+		rule Rule
+		id   *Identity
+		want bool
+	}{
+		{name: "empty rule", rule: Rule{}, id: staff, want: true},
+		{name: "matching MSP", rule: Rule{MSPIDs: []string{StaffMSP, AccountantMSP}}, id: staff, want: true},
+		{name: "other MSP", rule: Rule{MSPIDs: []string{ManagerMSP}}, id: staff, want: false},
+		{name: "matching OU ignores case", rule: Rule{OUs: []string{"payroll"}}, id: staff, want: true},
+		{name: "missing OU", rule: Rule{OUs: []string{"payroll"}}, id: manager, want: false},
+		{name: "MSP and OU", rule: Rule{MSPIDs: []string{ManagerMSP}, OUs: []string{"payroll"}}, id: staff, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rule.Allows(tt.id)
+			if err != nil {
+				t.Fatalf("Allows() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleString(t *testing.T) {
+	tests := []struct {
+		rule Rule
+		want string
+	}{
+		{Rule{}, "any caller"},
+		{Rule{MSPIDs: []string{StaffMSP, ManagerMSP}}, "OrgStaffMSP or OrgManagerMSP"},
+		{Admin, "OrgManagerMSP with role=admin"},
+		{Audit, "OrgManagerMSP or an auditor"},
+		{Rule{OUs: []string{"payroll"}, Attributes: map[string]string{"role": "admin", "dept": "hr"}}, "OU payroll with dept=hr with role=admin"},
+		{Admin.OrGovernedBy(Board), "OrgManagerMSP with role=admin or a proposal approved in multisign by signer group board with a yes weight of at least 2"},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestRuleAdmits(t *testing.T) {
+	rule := Admin.OrGovernedBy(Governance{Chaincode: GovernorChaincode, SignerGroups: []string{"board", "treasury"}, MinApproval: 3})
+	tests := []struct {
+		name     string
+		rule     Rule
+		governor string
+		approval Approval
+		want     bool
+	}{
+		{name: "listed group with enough weight", rule: rule, governor: GovernorChaincode, approval: Approval{RequestID: "r", SignerGroup: "treasury", YesWeight: 3}, want: true},
+		{name: "weight above minimum", rule: rule, governor: GovernorChaincode, approval: Approval{RequestID: "r", SignerGroup: "board", YesWeight: 7}, want: true},
+		{name: "weight below minimum", rule: rule, governor: GovernorChaincode, approval: Approval{RequestID: "r", SignerGroup: "board", YesWeight: 2}, want: false},
+		{name: "unlisted group", rule: rule, governor: GovernorChaincode, approval: Approval{RequestID: "r", SignerGroup: "payroll", YesWeight: 9}, want: false},
+		{name: "other governor", rule: rule, governor: "token_erc20", approval: Approval{RequestID: "r", SignerGroup: "board", YesWeight: 9}, want: false},
+		{name: "ungoverned rule", rule: Admin, governor: GovernorChaincode, approval: Approval{RequestID: "r", SignerGroup: "board", YesWeight: 9}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Admits(tt.governor, &tt.approval); got != tt.want {
+				t.Errorf("Admits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrGovernedByCopies(t *testing.T) {
+	base := Rule{MSPIDs: []string{ManagerMSP}}
+	governed := base.OrGovernedBy(Board)
+	if len(base.Governors) != 0 {
+		t.Errorf("OrGovernedBy changed the original rule: %v", base.Governors)
+	}
+	if len(governed.Governors) != 1 {
+		t.Errorf("OrGovernedBy() has %d governors, want 1", len(governed.Governors))
+	}
+}
+
+func TestAccountMSPID(t *testing.T) {
+	creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: AccountantMSP, IdBytes: []byte("cert")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		account string
+		want    string
+		wantErr bool
+	}{
+		{name: "serialized identity", account: hex.EncodeToString(creator), want: AccountantMSP},
+		{name: "not hex", account: "user8@orgstaff", wantErr: true},
+		{name: "not an identity", account: "00ff", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AccountMSPID(tt.account)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AccountMSPID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("AccountMSPID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
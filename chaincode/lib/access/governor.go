@@ -0,0 +1,148 @@
+package access
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/utils"
+)
+
+// GovernorChaincode is the governance chaincode. Rules that name it in
+// Governors admit calls it makes when executing an approved proposal
+const GovernorChaincode = "multisign"
+
+// GovernorFunction is the function a governor executes approved proposals in.
+// Calls made from any other function of the governor are not trusted
+const GovernorFunction = "finalizeRequest"
+
+// GovernedFunction is the function a governor calls to run a function of an
+// approved proposal: its arguments are the Approval as JSON, the function and
+// the function's arguments
+const GovernedFunction = "Governed"
+
+// Governance admits proposals that Chaincode executes once one of SignerGroups
+// approved them with a yes weight of at least MinApproval. Only admins set
+// signer groups, so the rule, not the requester, decides who has to agree
+type Governance struct {
+	Chaincode    string   `json:"chaincode"`
+	SignerGroups []string `json:"signerGroups"`
+	MinApproval  int      `json:"minApproval"`
+}
+
+// Board is the governance of administrative changes: proposals the "board"
+// signer group approved in multisign with a yes weight of at least 2
+var Board = Governance{Chaincode: GovernorChaincode, SignerGroups: []string{"board"}, MinApproval: 2}
+
+// String describes the governance for error messages and documentation
+func (g Governance) String() string {
+	return "a proposal approved in " + g.Chaincode + " by signer group " + strings.Join(g.SignerGroups, " or ") +
+		" with a yes weight of at least " + strconv.Itoa(g.MinApproval)
+}
+
+// Approval is what a governor vouches for when it runs an approved proposal:
+// the request, the signer group that decided it and the weight that voted yes
+type Approval struct {
+	RequestID   string `json:"requestId"`
+	SignerGroup string `json:"signerGroup"`
+	YesWeight   int    `json:"yesWeight"`
+}
+
+// TopLevelCall returns the chaincode and function the client invoked in this
+// transaction, which differs from the running chaincode when it was called
+// through InvokeChaincode
+func TopLevelCall(stub shim.ChaincodeStubInterface) (string, string, error) {
+	signedProposal, err := stub.GetSignedProposal()
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to get signed proposal: %s", err)
+	}
+	proposal, err := utils.GetProposal(signedProposal.GetProposalBytes())
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to parse proposal: %s", err)
+	}
+	cis, err := utils.GetChaincodeInvocationSpec(proposal)
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to parse invocation spec: %s", err)
+	}
+	spec := cis.GetChaincodeSpec()
+	function := ""
+	if args := spec.GetInput().GetArgs(); len(args) > 0 {
+		function = string(args[0])
+	}
+	return spec.GetChaincodeId().GetName(), function, nil
+}
+
+// OrGovernedBy returns a copy of the rule that also admits proposals approved
+// as the given governances require, whoever finalizes them
+func (r Rule) OrGovernedBy(governances ...Governance) Rule {
+	r.Governors = append(r.Governors[:len(r.Governors):len(r.Governors)], governances...)
+	return r
+}
+
+// Admits reports whether a proposal the governor executes with approval
+// satisfies one of the rule's governances
+func (r Rule) Admits(governor string, approval *Approval) bool {
+	for _, g := range r.Governors {
+		if g.Chaincode == governor && contains(g.SignerGroups, approval.SignerGroup) && approval.YesWeight >= g.MinApproval {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyApproval decodes the Approval a governor passed and returns it with the
+// governor's name. It fails unless the client invoked GovernorFunction of the
+// governor, which is then the only code that can have called this chaincode
+func VerifyApproval(stub shim.ChaincodeStubInterface, approvalJSON string) (string, *Approval, error) {
+	governor, function, err := TopLevelCall(stub)
+	if err != nil {
+		return "", nil, err
+	}
+	if function != GovernorFunction {
+		return "", nil, errcode.Newf(errcode.ForbiddenCode, "%s is only accepted from a governor executing an approved proposal", GovernedFunction)
+	}
+
+	var approval Approval
+	decoder := json.NewDecoder(bytes.NewReader([]byte(approvalJSON)))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&approval)
+	if err != nil {
+		return "", nil, errcode.Newf(errcode.InvalidArgumentCode, "Invalid approval: %s", err)
+	}
+	if approval.RequestID == "" || approval.SignerGroup == "" {
+		return "", nil, errcode.New(errcode.ForbiddenCode, "The approval names no request or signer group")
+	}
+	return governor, &approval, nil
+}
+
+// GovernedApproval returns the governor and the Approval of the running call,
+// which must have been made through GovernedFunction. Functions whose
+// governance depends on their arguments, such as GovernedTransfer, check it
+// themselves
+func GovernedApproval(stub shim.ChaincodeStubInterface) (string, *Approval, error) {
+	args := stub.GetStringArgs()
+	if len(args) < 2 || args[0] != GovernedFunction {
+		return "", nil, errcode.New(errcode.ForbiddenCode, "Only a governor executing an approved proposal can call this function")
+	}
+	return VerifyApproval(stub, args[1])
+}
+
+// AuthorizeGoverned returns a *DeniedError unless the rule admits a proposal
+// the governor executes with approval. A nil rule admits every proposal
+func AuthorizeGoverned(stub shim.ChaincodeStubInterface, function string, rule *Rule, readOnly bool, governor string, approval *Approval) error {
+	if !readOnly {
+		err := CheckWrite(stub, function)
+		if err != nil {
+			return err
+		}
+	}
+	if rule == nil || rule.Admits(governor, approval) {
+		return nil
+	}
+	reason := fmt.Sprintf("requires %s, but signer group %s approved it with a yes weight of %d", rule, approval.SignerGroup, approval.YesWeight)
+	return &DeniedError{Function: function, Reason: reason, MSPID: governor}
+}
@@ -0,0 +1,214 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chaincode/lib/errcode"
+)
+
+// formats validates the Format of string arguments. Integer formats are checked
+// after the value has parsed as an integer
+var formats = map[string]func(value string) bool{
+	// A hex-encoded serialized identity, or an alias such as "user8@orgstaff"
+	"account": regexp.MustCompile(`^(([0-9a-fA-F]{2})+|[a-zA-Z][a-zA-Z0-9.-]{2,31}(@[a-zA-Z0-9-]{2,31})?)$`).MatchString,
+	// An Ethereum address such as 0xf7D8dA6a7a04aCdAe76421F07CF29f38f93F1Ed2
+	"eth-address": regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`).MatchString,
+	"json":        func(value string) bool { return json.Valid([]byte(value)) },
+	"unix-time":   func(value string) bool { return !strings.HasPrefix(value, "-") },
+}
+
+// argumentError reports an invalid argument with its name in the details
+func argumentError(arg Arg, format string, args ...interface{}) error {
+	return errcode.Newf(errcode.InvalidArgumentCode, format, args...).With("argument", arg.Name)
+}
+
+// ParseArgs validates args against the declared arguments and returns them in
+// positional form. Besides positional strings, callers may pass a single JSON
+// object of named arguments, e.g. {"to":"user9@orgstaff","amount":50}; its
+// values are coerced to the strings a positional call would pass.
+//
+// Required arguments must be non-empty; an empty optional argument counts as
+// omitted. Values are checked against the argument's type, enum, pattern,
+// length and format, and integers and booleans are returned in canonical form
+func ParseArgs(declared []Arg, args []string) ([]string, error) {
+	if isNamed(declared, args) {
+		var err error
+		args, err = positional(declared, args[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	required := 0
+	for i, arg := range declared {
+		if !arg.Optional {
+			required = i + 1
+		}
+	}
+	if len(args) < required || len(args) > len(declared) {
+		expecting := fmt.Sprintf("%d", len(declared))
+		if required < len(declared) {
+			expecting = fmt.Sprintf("%d to %d", required, len(declared))
+		}
+		for i, arg := range declared {
+			if i == 0 {
+				expecting += ": "
+			} else {
+				expecting += ", "
+			}
+			expecting += arg.Name
+		}
+		return nil, errcode.Newf(errcode.InvalidArgumentCode, "Incorrect number of arguments. Expecting %s", expecting).With("count", len(args))
+	}
+
+	parsed := make([]string, len(args))
+	for i, value := range args {
+		arg := declared[i]
+		if value == "" {
+			if !arg.Optional {
+				return nil, argumentError(arg, "Argument %s must not be empty", arg.Name)
+			}
+			continue
+		}
+		value, err := arg.parse(value)
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = value
+	}
+	return parsed, nil
+}
+
+// isNamed reports whether args is a single JSON object of named arguments. A
+// lone argument that is itself declared as JSON is taken positionally
+func isNamed(declared []Arg, args []string) bool {
+	if len(args) != 1 || !strings.HasPrefix(strings.TrimSpace(args[0]), "{") {
+		return false
+	}
+	return len(declared) == 0 || declared[0].Format != "json"
+}
+
+// positional converts a JSON object of named arguments to positional strings,
+// leaving omitted arguments empty
+func positional(declared []Arg, object string) ([]string, error) {
+	var named map[string]json.RawMessage
+	decoder := json.NewDecoder(strings.NewReader(object))
+	decoder.UseNumber()
+	err := decoder.Decode(&named)
+	if err != nil {
+		return nil, errcode.Newf(errcode.InvalidArgumentCode, "Invalid named arguments: %s", err)
+	}
+
+	args := make([]string, 0, len(declared))
+	last := 0
+	for _, arg := range declared {
+		raw, exists := named[arg.Name]
+		delete(named, arg.Name)
+		value := ""
+		if exists {
+			value, err = coerce(arg, raw)
+			if err != nil {
+				return nil, err
+			}
+			last = len(args) + 1
+		}
+		args = append(args, value)
+	}
+	for name := range named {
+		return nil, errcode.Newf(errcode.InvalidArgumentCode, "Unknown argument %s", name).With("argument", name)
+	}
+	return args[:last], nil
+}
+
+// coerce turns a named JSON value into the string a positional call would pass.
+// Strings are taken as they are, numbers and booleans by their JSON text, and
+// objects and arrays only where the argument is declared as JSON
+func coerce(arg Arg, raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	switch raw[0] {
+	case '"':
+		var value string
+		err := json.Unmarshal(raw, &value)
+		if err != nil {
+			return "", argumentError(arg, "Invalid argument %s: %s", arg.Name, err)
+		}
+		return value, nil
+	case '{', '[':
+		if arg.Format != "json" {
+			return "", argumentError(arg, "Argument %s must be a %s, not an object or array", arg.Name, arg.Type)
+		}
+	}
+	return string(raw), nil
+}
+
+// parse checks one non-empty value and returns it in canonical form
+func (arg Arg) parse(value string) (string, error) {
+	switch arg.Type {
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			// Amounts are unsigned and may exceed the int64 range
+			u, uerr := strconv.ParseUint(value, 10, 64)
+			if uerr != nil {
+				return "", argumentError(arg, "Argument %s must be an integer, got %q", arg.Name, value)
+			}
+			value = strconv.FormatUint(u, 10)
+		} else {
+			value = strconv.FormatInt(n, 10)
+		}
+	case "number":
+		_, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", argumentError(arg, "Argument %s must be a number, got %q", arg.Name, value)
+		}
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", argumentError(arg, "Argument %s must be true or false, got %q", arg.Name, value)
+		}
+		value = strconv.FormatBool(b)
+	}
+
+	if len(arg.Enum) > 0 && !contains(arg.Enum, value) {
+		return "", argumentError(arg, "Argument %s must be one of %s, got %q", arg.Name, strings.Join(arg.Enum, ", "), value)
+	}
+	length := utf8.RuneCountInString(value)
+	if arg.MinLength > 0 && length < arg.MinLength {
+		return "", argumentError(arg, "Argument %s must be at least %d characters", arg.Name, arg.MinLength)
+	}
+	if arg.MaxLength > 0 && length > arg.MaxLength {
+		return "", argumentError(arg, "Argument %s must be at most %d characters", arg.Name, arg.MaxLength)
+	}
+	if arg.Pattern != "" {
+		// Anchor the pattern so it must match the whole value, not a substring
+		matched, err := regexp.MatchString(`^(?:`+arg.Pattern+`)$`, value)
+		if err != nil {
+			return "", errcode.Newf(errcode.InternalCode, "Invalid pattern for argument %s: %s", arg.Name, err)
+		}
+		if !matched {
+			return "", argumentError(arg, "Argument %s must match %s, got %q", arg.Name, arg.Pattern, value)
+		}
+	}
+	if valid, exists := formats[arg.Format]; exists && !valid(value) {
+		return "", argumentError(arg, "Argument %s is not a valid %s, got %q", arg.Name, arg.Format, value)
+	}
+	return value, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
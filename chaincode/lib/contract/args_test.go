@@ -0,0 +1,95 @@
+package contract
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chaincode/lib/errcode"
+)
+
+func TestParseArgs(t *testing.T) {
+	transfer := []Arg{
+		{Name: "to", Type: "string", Format: "account"},
+		{Name: "amount", Type: "integer"},
+		{Name: "memo", Type: "string", Optional: true, MaxLength: 10},
+	}
+	tests := []struct {
+		name     string
+		declared []Arg
+		args     []string
+		want     []string
+		err      string
+	}{
+		{name: "positional", declared: transfer, args: []string{"user9@orgstaff", "50"}, want: []string{"user9@orgstaff", "50"}},
+		{name: "optional given", declared: transfer, args: []string{"0a0b", "50", "salary"}, want: []string{"0a0b", "50", "salary"}},
+		{name: "canonical integer", declared: transfer, args: []string{"0a0b", "050"}, want: []string{"0a0b", "50"}},
+		{name: "uint64 beyond int64", declared: transfer, args: []string{"0a0b", "18446744073709551615"}, want: []string{"0a0b", "18446744073709551615"}},
+		{name: "named", declared: transfer, args: []string{`{"to":"0a0b","amount":50}`}, want: []string{"0a0b", "50"}},
+		{name: "named with optional", declared: transfer, args: []string{`{"amount":"7","to":"0a0b","memo":"rent"}`}, want: []string{"0a0b", "7", "rent"}},
+		{name: "too few", declared: transfer, args: []string{"0a0b"}, err: "Expecting 2 to 3: to, amount, memo"},
+		{name: "too many", declared: transfer, args: []string{"0a0b", "1", "a", "b"}, err: "Incorrect number of arguments"},
+		{name: "empty required", declared: transfer, args: []string{"", "50"}, err: "Argument to must not be empty"},
+		{name: "not an integer", declared: transfer, args: []string{"0a0b", "ten"}, err: "Argument amount must be an integer"},
+		{name: "too long", declared: transfer, args: []string{"0a0b", "1", "a long memo"}, err: "at most 10 characters"},
+		{name: "bad account", declared: transfer, args: []string{"x!", "1"}, err: "is not a valid account"},
+		{name: "unknown named", declared: transfer, args: []string{`{"to":"0a0b","amount":1,"fee":2}`}, err: "Unknown argument fee"},
+		{name: "named object where not json", declared: transfer, args: []string{`{"to":{"a":1},"amount":1}`}, err: "not an object or array"},
+		{name: "lone json argument is positional", declared: []Arg{{Name: "doc", Type: "string", Format: "json"}}, args: []string{`{"a":1}`}, want: []string{`{"a":1}`}},
+		{name: "invalid json", declared: []Arg{{Name: "doc", Type: "string", Format: "json"}}, args: []string{`{"a":`}, err: "is not a valid json"},
+		{name: "enum", declared: []Arg{{Name: "vote", Type: "string", Enum: []string{"yes", "no"}}}, args: []string{"maybe"}, err: "must be one of yes, no"},
+		{name: "boolean", declared: []Arg{{Name: "flag", Type: "boolean"}}, args: []string{"TRUE"}, want: []string{"true"}},
+		{name: "min length", declared: []Arg{{Name: "id", Type: "string", MinLength: 3}}, args: []string{"ab"}, err: "at least 3 characters"},
+		{name: "pattern matches whole value", declared: []Arg{{Name: "code", Type: "string", Pattern: "[A-Z]{3}"}}, args: []string{"TPY"}, want: []string{"TPY"}},
+		{name: "pattern is anchored", declared: []Arg{{Name: "code", Type: "string", Pattern: "[A-Z]{3}"}}, args: []string{"xTPYx"}, err: "must match"},
+		{name: "pattern alternation is anchored", declared: []Arg{{Name: "code", Type: "string", Pattern: "a|b"}}, args: []string{"ab"}, err: "must match"},
+		{name: "unix time", declared: []Arg{{Name: "at", Type: "integer", Format: "unix-time"}}, args: []string{"-5"}, err: "is not a valid unix-time"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseArgs(tt.declared, tt.args)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("ParseArgs(%q) error = %v, want %q", tt.args, err, tt.err)
+				}
+				if coded, ok := err.(errcode.Coded); !ok || coded.ErrorCode() != errcode.InvalidArgumentCode {
+					t.Errorf("ParseArgs(%q) error code is not %s", tt.args, errcode.InvalidArgumentCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseArgs(%q) error = %v", tt.args, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseArgs(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		value  string
+		valid  bool
+	}{
+		{"account", "0a1B", true},
+		{"account", "0a1", false},
+		{"account", "user8@orgstaff", true},
+		{"account", "treasury", true},
+		{"account", "8user", false},
+		{"account", "x", false},
+		{"eth-address", "0xf7D8dA6a7a04aCdAe76421F07CF29f38f93F1Ed2", true},
+		{"eth-address", "f7D8dA6a7a04aCdAe76421F07CF29f38f93F1Ed2", false},
+		{"eth-address", "0xf7D8dA6a7a04aCdAe76421F07CF29f38f93F1Ed", false},
+		{"json", `["a","b"]`, true},
+		{"json", `["a",`, false},
+		{"unix-time", "1700000000", true},
+		{"unix-time", "-1", false},
+	}
+	for _, tt := range tests {
+		if got := formats[tt.format](tt.value); got != tt.valid {
+			t.Errorf("%s(%q) = %v, want %v", tt.format, tt.value, got, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Package contract drives chaincode dispatch from a function catalogue, so
+// the GetMetadata schema callers read and the functions Invoke accepts come
+// from the same table and cannot drift apart.
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/chaincode/lib/access"
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// MetadataFunction is the built-in query every contract answers with its catalogue
+const MetadataFunction = "GetMetadata"
+
+// Handler implements one chaincode function
+type Handler func(stub shim.ChaincodeStubInterface, args []string) pb.Response
+
+// Arg describes one argument, passed by position or by name. Type is a JSON
+// Schema type; Format narrows it, e.g. "account" for a hex account ID or alias,
+// "eth-address" for an Ethereum address, "unix-time" for Unix seconds or
+// "json" for an embedded JSON document. Pattern is a regular expression the
+// whole value must match. See ParseArgs for how arguments are checked
+type Arg struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Format      string   `json:"format,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	MinLength   int      `json:"minLength,omitempty"`
+	MaxLength   int      `json:"maxLength,omitempty"`
+	Optional    bool     `json:"optional,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// Function is one entry of the catalogue. A nil Rule admits every member of
+// the channel; only ReadOnly functions may be called by auditors
+type Function struct {
+	Name        string
+	Description string
+	Args        []Arg
+	Returns     string
+	ReadOnly    bool
+	Rule        *access.Rule
+	Handler     Handler
+}
+
+// Contract is a chaincode's function catalogue
+type Contract struct {
+	Name        string
+	Description string
+	Functions   []Function
+}
+
+// FunctionMetadata is the published description of a function
+type FunctionMetadata struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Args        []Arg        `json:"args"`
+	Returns     string       `json:"returns"`
+	ReadOnly    bool         `json:"readOnly"`
+	Access      string       `json:"access"`
+	Rule        *access.Rule `json:"rule,omitempty"`
+}
+
+// Metadata is the schema returned by GetMetadata
+type Metadata struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Functions   []FunctionMetadata `json:"functions"`
+}
+
+// metadataFunction describes the built-in GetMetadata query
+var metadataFunction = Function{
+	Name:        MetadataFunction,
+	Description: "Returns this catalogue: every function with its arguments, return format and who may call it",
+	Args:        []Arg{},
+	Returns:     "JSON metadata",
+	ReadOnly:    true,
+}
+
+// lookup returns the catalogue entry for name
+func (c *Contract) lookup(name string) (*Function, bool) {
+	for i := range c.Functions {
+		if c.Functions[i].Name == name {
+			return &c.Functions[i], true
+		}
+	}
+	return nil, false
+}
+
+// Metadata describes every function of the contract, GetMetadata included
+func (c *Contract) Metadata() Metadata {
+	metadata := Metadata{Name: c.Name, Description: c.Description}
+	functions := append(c.Functions[:len(c.Functions):len(c.Functions)], metadataFunction)
+	for _, f := range functions {
+		who := "any caller"
+		if f.Rule != nil {
+			who = f.Rule.String()
+		}
+		if !f.ReadOnly {
+			who += ", except auditors"
+		}
+		args := f.Args
+		if args == nil {
+			args = []Arg{}
+		}
+		metadata.Functions = append(metadata.Functions, FunctionMetadata{
+			Name:        f.Name,
+			Description: f.Description,
+			Args:        args,
+			Returns:     f.Returns,
+			ReadOnly:    f.ReadOnly,
+			Access:      who,
+			Rule:        f.Rule,
+		})
+	}
+	return metadata
+}
+
+// Invoke checks the caller against the function's rule, validates the arguments
+// and dispatches to the function's handler
+func (c *Contract) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	function, args := stub.GetFunctionAndParameters()
+	return c.Call(stub, function, args)
+}
+
+// Call runs function with args as Invoke would, checks included. Chaincodes
+// use it to run one of their own functions, such as an approved proposal
+func (c *Contract) Call(stub shim.ChaincodeStubInterface, function string, args []string) pb.Response {
+	if function == MetadataFunction {
+		metadataJSON, err := json.Marshal(c.Metadata())
+		if err != nil {
+			return errcode.Internal(fmt.Sprintf("Failed to marshal metadata: %s", err))
+		}
+		return shim.Success(metadataJSON)
+	}
+
+	if function == access.GovernedFunction {
+		return c.callGoverned(stub, args)
+	}
+
+	f, exists := c.lookup(function)
+	if !exists {
+		return invalidFunction(function)
+	}
+
+	// Check the caller against the function's access rule
+	err := access.Authorize(stub, f.Name, f.Rule, f.ReadOnly)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	return f.run(stub, args)
+}
+
+// callGoverned runs the function of an approved proposal a governor passed to
+// GovernedFunction, checking the approval against the function's rule instead
+// of the caller's identity
+func (c *Contract) callGoverned(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) < 2 {
+		return errcode.InvalidArgument(fmt.Sprintf("Incorrect number of arguments. Expecting at least 2 for %s: approval, function", access.GovernedFunction))
+	}
+	governor, approval, err := access.VerifyApproval(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	f, exists := c.lookup(args[1])
+	if !exists {
+		return invalidFunction(args[1])
+	}
+	err = access.AuthorizeGoverned(stub, f.Name, f.Rule, f.ReadOnly, governor, approval)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	return f.run(stub, args[2:])
+}
+
+// run validates the arguments, so handlers receive them in positional,
+// canonical form, and calls the handler
+func (f *Function) run(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	args, err := ParseArgs(f.Args, args)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	return f.Handler(stub, args)
+}
+
+func invalidFunction(function string) pb.Response {
+	return errcode.Newf(errcode.InvalidArgumentCode, "Invalid function name %s. Call %s for the list of functions", function, MetadataFunction).With("function", function).Response()
+}
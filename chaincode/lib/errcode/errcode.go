@@ -0,0 +1,149 @@
+// Package errcode gives chaincode errors a stable code and a matching response
+// status, so client applications can map them to HTTP statuses and localised
+// messages instead of parsing free text.
+//
+// A failed response carries the status of its code and, as its message, the
+// JSON envelope
+//
+//	{"code":"INSUFFICIENT_FUNDS","message":"Insufficient balance","details":{"balance":5,"amount":10}}
+package errcode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Code identifies a kind of failure. Codes are part of the chaincode API and never change
+type Code string
+
+// Error codes and the response status each is returned with
+const (
+	InvalidArgumentCode   Code = "INVALID_ARGUMENT"
+	ForbiddenCode         Code = "FORBIDDEN"
+	NotFoundCode          Code = "NOT_FOUND"
+	ConflictCode          Code = "CONFLICT"
+	InsufficientFundsCode Code = "INSUFFICIENT_FUNDS"
+	InternalCode          Code = "INTERNAL"
+)
+
+var statuses = map[Code]int32{
+	InvalidArgumentCode:   400,
+	ForbiddenCode:         403,
+	NotFoundCode:          404,
+	ConflictCode:          409,
+	InsufficientFundsCode: 422,
+	InternalCode:          500,
+}
+
+// Status returns the response status for the code
+func (c Code) Status() int32 {
+	if status, exists := statuses[c]; exists {
+		return status
+	}
+	return statuses[InternalCode]
+}
+
+// Coded is implemented by errors from other packages that know their code
+type Coded interface {
+	error
+	ErrorCode() Code
+}
+
+// Error is the envelope returned to clients
+type Error struct {
+	Code    Code                   `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// New returns an error with the given code
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Newf returns an error with the given code and a formatted message
+func Newf(code Code, format string, args ...interface{}) *Error {
+	return New(code, fmt.Sprintf(format, args...))
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// ErrorCode implements Coded
+func (e *Error) ErrorCode() Code {
+	return e.Code
+}
+
+// With adds a machine-readable detail to the error
+func (e *Error) With(key string, value interface{}) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+	e.Details[key] = value
+	return e
+}
+
+// Response returns the failed chaincode response carrying the envelope
+func (e *Error) Response() pb.Response {
+	envelope, err := json.Marshal(e)
+	if err != nil {
+		envelope = []byte(fmt.Sprintf(`{"code":%q,"message":%q}`, InternalCode, e.Message))
+	}
+	return pb.Response{Status: e.Code.Status(), Message: string(envelope)}
+}
+
+// FromError returns the response for err, keeping its code if it has one.
+// Any other error is reported as INTERNAL
+func FromError(err error) pb.Response {
+	switch e := err.(type) {
+	case *Error:
+		return e.Response()
+	case Coded:
+		return New(e.ErrorCode(), e.Error()).Response()
+	}
+	return New(InternalCode, err.Error()).Response()
+}
+
+// Parse recovers the error from a failed response, such as one returned by
+// InvokeChaincode. Responses without an envelope are reported as INTERNAL
+func Parse(response pb.Response) *Error {
+	var e Error
+	err := json.Unmarshal([]byte(response.Message), &e)
+	if err != nil || e.Code == "" {
+		return New(InternalCode, response.Message)
+	}
+	return &e
+}
+
+// InvalidArgument rejects malformed or out-of-range arguments
+func InvalidArgument(message string) pb.Response {
+	return New(InvalidArgumentCode, message).Response()
+}
+
+// Forbidden rejects a caller who may not perform the operation
+func Forbidden(message string) pb.Response {
+	return New(ForbiddenCode, message).Response()
+}
+
+// NotFound reports that the requested object does not exist
+func NotFound(message string) pb.Response {
+	return New(NotFoundCode, message).Response()
+}
+
+// Conflict rejects an operation that clashes with the current state
+func Conflict(message string) pb.Response {
+	return New(ConflictCode, message).Response()
+}
+
+// InsufficientFunds rejects a payment the balance or allowance cannot cover
+func InsufficientFunds(message string) pb.Response {
+	return New(InsufficientFundsCode, message).Response()
+}
+
+// Internal reports a failure of the chaincode or the ledger rather than the request
+func Internal(message string) pb.Response {
+	return New(InternalCode, message).Response()
+}
@@ -0,0 +1,158 @@
+// Package export writes paginated JSON Lines for the auditor export queries of
+// the token_erc20, multisign and database chaincodes.
+//
+// A page holds one JSON object per line followed by a final "page" line such as
+//
+//	{"page":{"count":2,"bookmark":"...","txId":"...","timestamp":1700000000}}
+//
+// Callers pass the bookmark back to fetch the next page; it is empty on the last.
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// DefaultPageSize is used when the caller does not give a page size
+const DefaultPageSize = 100
+
+// MaxPageSize bounds a single page so responses stay well below the gRPC limit
+const MaxPageSize = 1000
+
+// PageInfo is the trailing line of every page
+type PageInfo struct {
+	Count     int    `json:"count"`
+	Bookmark  string `json:"bookmark"`
+	TxID      string `json:"txId"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Page collects the records of one export page. Records must be added in
+// ascending key order so the last key can serve as the bookmark
+type Page struct {
+	size    int
+	after   string
+	lastKey string
+	count   int
+	full    bool
+	buf     bytes.Buffer
+}
+
+// ParsePageSize converts the optional page size argument
+func ParsePageSize(arg string) (int, error) {
+	if arg == "" {
+		return DefaultPageSize, nil
+	}
+	size, err := strconv.Atoi(arg)
+	if err != nil || size <= 0 || size > MaxPageSize {
+		return 0, errcode.Newf(errcode.InvalidArgumentCode, "Page size must be between 1 and %d", MaxPageSize)
+	}
+	return size, nil
+}
+
+// NewPage starts a page of at most size records after bookmark
+func NewPage(size int, bookmark string) (*Page, error) {
+	after, err := base64.StdEncoding.DecodeString(bookmark)
+	if err != nil {
+		return nil, errcode.Newf(errcode.InvalidArgumentCode, "Invalid bookmark: %s", err)
+	}
+	return &Page{size: size, after: string(after)}, nil
+}
+
+// PageArgs starts a page from the optional trailing pageSize and bookmark
+// arguments shared by every export query
+func PageArgs(args []string) (*Page, error) {
+	sizeArg, bookmark := "", ""
+	if len(args) > 0 {
+		sizeArg = args[0]
+	}
+	if len(args) > 1 {
+		bookmark = args[1]
+	}
+	size, err := ParsePageSize(sizeArg)
+	if err != nil {
+		return nil, err
+	}
+	return NewPage(size, bookmark)
+}
+
+// Skip reports whether key was exported on an earlier page
+func (p *Page) Skip(key string) bool {
+	return p.after != "" && key <= p.after
+}
+
+// Add appends record, stored under key, as one line. It returns false without
+// adding anything once the page is full
+func (p *Page) Add(key string, record interface{}) (bool, error) {
+	if p.count == p.size {
+		p.full = true
+		return false, nil
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return false, fmt.Errorf("Failed to marshal %s: %s", key, err)
+	}
+	p.buf.Write(line)
+	p.buf.WriteByte('\n')
+	p.count++
+	p.lastKey = key
+	return true, nil
+}
+
+// Bytes finishes the page with its page line, stamped with the transaction so
+// auditors can tie the export to a point in the ledger
+func (p *Page) Bytes(stub shim.ChaincodeStubInterface) ([]byte, error) {
+	info := PageInfo{Count: p.count, TxID: stub.GetTxID()}
+	if p.full {
+		info.Bookmark = base64.StdEncoding.EncodeToString([]byte(p.lastKey))
+	}
+	ts, err := stub.GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get transaction timestamp: %s", err)
+	}
+	info.Timestamp = ts.GetSeconds()
+	line, err := json.Marshal(map[string]PageInfo{"page": info})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal page: %s", err)
+	}
+	p.buf.Write(line)
+	p.buf.WriteByte('\n')
+	return p.buf.Bytes(), nil
+}
+
+// Iterate adds every record of iterator not exported on an earlier page until
+// the page is full. record converts a stored value into the exported object;
+// it returns nil to leave a value out
+func (p *Page) Iterate(iterator shim.StateQueryIteratorInterface, record func(key string, value []byte) (interface{}, error)) error {
+	defer iterator.Close()
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return err
+		}
+		if p.Skip(kv.Key) {
+			continue
+		}
+		r, err := record(kv.Key, kv.Value)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			continue
+		}
+		added, err := p.Add(kv.Key, r)
+		if err != nil {
+			return err
+		}
+		if !added {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package export
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+)
+
+// sliceIterator iterates over fixed key-value pairs
+type sliceIterator struct {
+	kvs []*queryresult.KV
+}
+
+func (i *sliceIterator) HasNext() bool { return len(i.kvs) > 0 }
+func (i *sliceIterator) Close() error  { return nil }
+
+func (i *sliceIterator) Next() (*queryresult.KV, error) {
+	kv := i.kvs[0]
+	i.kvs = i.kvs[1:]
+	return kv, nil
+}
+
+func records(keys ...string) *sliceIterator {
+	iterator := &sliceIterator{}
+	for _, key := range keys {
+		iterator.kvs = append(iterator.kvs, &queryresult.KV{Key: key, Value: []byte(key)})
+	}
+	return iterator
+}
+
+func TestParsePageSize(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    int
+		wantErr bool
+	}{
+		{"", DefaultPageSize, false},
+		{"1", 1, false},
+		{"1000", MaxPageSize, false},
+		{"1001", 0, true},
+		{"0", 0, true},
+		{"-3", 0, true},
+		{"ten", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePageSize(tt.arg)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParsePageSize(%q) = %d, %v; want %d, error %v", tt.arg, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPageArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "defaults", args: nil},
+		{name: "size only", args: []string{"10"}},
+		{name: "size and bookmark", args: []string{"10", base64.StdEncoding.EncodeToString([]byte("k"))}},
+		{name: "bad bookmark", args: []string{"10", "%%%"}, wantErr: true},
+		{name: "bad size", args: []string{"big"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := PageArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PageArgs(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// pageLines runs one page over keys and returns its record lines and page info
+func pageLines(t *testing.T, size int, bookmark string, keys ...string) ([]string, PageInfo) {
+	t.Helper()
+	page, err := NewPage(size, bookmark)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = page.Iterate(records(keys...), func(key string, value []byte) (interface{}, error) {
+		if strings.HasPrefix(key, "skip") {
+			return nil, nil
+		}
+		return string(value), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stub := shim.NewMockStub("export", nil)
+	stub.MockTransactionStart("tx1")
+	defer stub.MockTransactionEnd("tx1")
+	pageBytes, err := page.Bytes(stub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(pageBytes), "\n"), "\n")
+	var last map[string]PageInfo
+	err = json.Unmarshal([]byte(lines[len(lines)-1]), &last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lines[:len(lines)-1], last["page"]
+}
+
+func TestPageIterate(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		after    string
+		keys     []string
+		want     []string
+		bookmark string
+	}{
+		{name: "fits", size: 5, keys: []string{"a", "b"}, want: []string{`"a"`, `"b"`}},
+		{name: "exactly full has no more", size: 2, keys: []string{"a", "b"}, want: []string{`"a"`, `"b"`}},
+		{name: "overflow sets bookmark", size: 2, keys: []string{"a", "b", "c"}, want: []string{`"a"`, `"b"`}, bookmark: "b"},
+		{name: "resumes after bookmark", size: 2, after: "b", keys: []string{"a", "b", "c"}, want: []string{`"c"`}},
+		{name: "left out records do not count", size: 2, keys: []string{"a", "skip1", "b"}, want: []string{`"a"`, `"b"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bookmark := base64.StdEncoding.EncodeToString([]byte(tt.after))
+			lines, info := pageLines(t, tt.size, bookmark, tt.keys...)
+			if strings.Join(lines, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("lines = %q, want %q", lines, tt.want)
+			}
+			if info.Count != len(tt.want) {
+				t.Errorf("count = %d, want %d", info.Count, len(tt.want))
+			}
+			wantBookmark := ""
+			if tt.bookmark != "" {
+				wantBookmark = base64.StdEncoding.EncodeToString([]byte(tt.bookmark))
+			}
+			if info.Bookmark != wantBookmark {
+				t.Errorf("bookmark = %q, want %q", info.Bookmark, wantBookmark)
+			}
+			if info.TxID != "tx1" {
+				t.Errorf("txId = %q, want tx1", info.TxID)
+			}
+		})
+	}
+}
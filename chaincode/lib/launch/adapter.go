@@ -0,0 +1,216 @@
+//go:build external
+// +build external
+
+package launch
+
+import (
+	"github.com/golang/protobuf/ptypes/timestamp"
+	servershim "github.com/hyperledger/fabric-chaincode-go/shim"
+	serverresult "github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	serverpb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// chaincodeAdapter serves a chaincode written against the Fabric 1.4 shim
+// through the Fabric 2.x shim. The two shims speak the same protocol but
+// declare their own stub and message types, so every call is translated
+type chaincodeAdapter struct {
+	cc shim.Chaincode
+}
+
+func (a *chaincodeAdapter) Init(stub servershim.ChaincodeStubInterface) serverpb.Response {
+	return toServerResponse(a.cc.Init(&stubAdapter{stub: stub}))
+}
+
+func (a *chaincodeAdapter) Invoke(stub servershim.ChaincodeStubInterface) serverpb.Response {
+	return toServerResponse(a.cc.Invoke(&stubAdapter{stub: stub}))
+}
+
+func toServerResponse(response pb.Response) serverpb.Response {
+	return serverpb.Response{Status: response.Status, Message: response.Message, Payload: response.Payload}
+}
+
+func fromServerMetadata(metadata *serverpb.QueryResponseMetadata) *pb.QueryResponseMetadata {
+	if metadata == nil {
+		return nil
+	}
+	return &pb.QueryResponseMetadata{FetchedRecordsCount: metadata.FetchedRecordsCount, Bookmark: metadata.Bookmark}
+}
+
+// stubAdapter presents a Fabric 2.x stub as a Fabric 1.4 one
+type stubAdapter struct {
+	stub servershim.ChaincodeStubInterface
+}
+
+func (s *stubAdapter) GetArgs() [][]byte       { return s.stub.GetArgs() }
+func (s *stubAdapter) GetStringArgs() []string { return s.stub.GetStringArgs() }
+func (s *stubAdapter) GetFunctionAndParameters() (string, []string) {
+	return s.stub.GetFunctionAndParameters()
+}
+func (s *stubAdapter) GetArgsSlice() ([]byte, error) { return s.stub.GetArgsSlice() }
+func (s *stubAdapter) GetTxID() string               { return s.stub.GetTxID() }
+func (s *stubAdapter) GetChannelID() string          { return s.stub.GetChannelID() }
+
+func (s *stubAdapter) InvokeChaincode(chaincodeName string, args [][]byte, channel string) pb.Response {
+	response := s.stub.InvokeChaincode(chaincodeName, args, channel)
+	return pb.Response{Status: response.Status, Message: response.Message, Payload: response.Payload}
+}
+
+func (s *stubAdapter) GetState(key string) ([]byte, error)     { return s.stub.GetState(key) }
+func (s *stubAdapter) PutState(key string, value []byte) error { return s.stub.PutState(key, value) }
+func (s *stubAdapter) DelState(key string) error               { return s.stub.DelState(key) }
+
+func (s *stubAdapter) SetStateValidationParameter(key string, ep []byte) error {
+	return s.stub.SetStateValidationParameter(key, ep)
+}
+
+func (s *stubAdapter) GetStateValidationParameter(key string) ([]byte, error) {
+	return s.stub.GetStateValidationParameter(key)
+}
+
+func (s *stubAdapter) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	return stateIterator(s.stub.GetStateByRange(startKey, endKey))
+}
+
+func (s *stubAdapter) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	return pagedStateIterator(s.stub.GetStateByRangeWithPagination(startKey, endKey, pageSize, bookmark))
+}
+
+func (s *stubAdapter) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	return stateIterator(s.stub.GetStateByPartialCompositeKey(objectType, keys))
+}
+
+func (s *stubAdapter) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	return pagedStateIterator(s.stub.GetStateByPartialCompositeKeyWithPagination(objectType, keys, pageSize, bookmark))
+}
+
+func (s *stubAdapter) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return s.stub.CreateCompositeKey(objectType, attributes)
+}
+
+func (s *stubAdapter) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	return s.stub.SplitCompositeKey(compositeKey)
+}
+
+func (s *stubAdapter) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	return stateIterator(s.stub.GetQueryResult(query))
+}
+
+func (s *stubAdapter) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	return pagedStateIterator(s.stub.GetQueryResultWithPagination(query, pageSize, bookmark))
+}
+
+func (s *stubAdapter) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	iterator, err := s.stub.GetHistoryForKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &historyIteratorAdapter{iterator: iterator}, nil
+}
+
+func (s *stubAdapter) GetPrivateData(collection, key string) ([]byte, error) {
+	return s.stub.GetPrivateData(collection, key)
+}
+
+func (s *stubAdapter) PutPrivateData(collection string, key string, value []byte) error {
+	return s.stub.PutPrivateData(collection, key, value)
+}
+
+func (s *stubAdapter) DelPrivateData(collection, key string) error {
+	return s.stub.DelPrivateData(collection, key)
+}
+
+func (s *stubAdapter) SetPrivateDataValidationParameter(collection, key string, ep []byte) error {
+	return s.stub.SetPrivateDataValidationParameter(collection, key, ep)
+}
+
+func (s *stubAdapter) GetPrivateDataValidationParameter(collection, key string) ([]byte, error) {
+	return s.stub.GetPrivateDataValidationParameter(collection, key)
+}
+
+func (s *stubAdapter) GetPrivateDataByRange(collection, startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	return stateIterator(s.stub.GetPrivateDataByRange(collection, startKey, endKey))
+}
+
+func (s *stubAdapter) GetPrivateDataByPartialCompositeKey(collection, objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	return stateIterator(s.stub.GetPrivateDataByPartialCompositeKey(collection, objectType, keys))
+}
+
+func (s *stubAdapter) GetPrivateDataQueryResult(collection, query string) (shim.StateQueryIteratorInterface, error) {
+	return stateIterator(s.stub.GetPrivateDataQueryResult(collection, query))
+}
+
+func (s *stubAdapter) GetCreator() ([]byte, error)                   { return s.stub.GetCreator() }
+func (s *stubAdapter) GetTransient() (map[string][]byte, error)      { return s.stub.GetTransient() }
+func (s *stubAdapter) GetBinding() ([]byte, error)                   { return s.stub.GetBinding() }
+func (s *stubAdapter) GetDecorations() map[string][]byte             { return s.stub.GetDecorations() }
+func (s *stubAdapter) GetTxTimestamp() (*timestamp.Timestamp, error) { return s.stub.GetTxTimestamp() }
+
+func (s *stubAdapter) GetSignedProposal() (*pb.SignedProposal, error) {
+	proposal, err := s.stub.GetSignedProposal()
+	if err != nil || proposal == nil {
+		return nil, err
+	}
+	return &pb.SignedProposal{ProposalBytes: proposal.ProposalBytes, Signature: proposal.Signature}, nil
+}
+
+func (s *stubAdapter) SetEvent(name string, payload []byte) error {
+	return s.stub.SetEvent(name, payload)
+}
+
+func stateIterator(iterator servershim.StateQueryIteratorInterface, err error) (shim.StateQueryIteratorInterface, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &stateIteratorAdapter{iterator: iterator}, nil
+}
+
+func pagedStateIterator(iterator servershim.StateQueryIteratorInterface, metadata *serverpb.QueryResponseMetadata, err error) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if err != nil {
+		return nil, nil, err
+	}
+	return &stateIteratorAdapter{iterator: iterator}, fromServerMetadata(metadata), nil
+}
+
+// stateIteratorAdapter presents a Fabric 2.x state iterator as a Fabric 1.4 one
+type stateIteratorAdapter struct {
+	iterator servershim.StateQueryIteratorInterface
+}
+
+func (i *stateIteratorAdapter) HasNext() bool { return i.iterator.HasNext() }
+func (i *stateIteratorAdapter) Close() error  { return i.iterator.Close() }
+
+func (i *stateIteratorAdapter) Next() (*queryresult.KV, error) {
+	kv, err := i.iterator.Next()
+	if err != nil || kv == nil {
+		return nil, err
+	}
+	return fromServerKV(kv), nil
+}
+
+func fromServerKV(kv *serverresult.KV) *queryresult.KV {
+	return &queryresult.KV{Namespace: kv.Namespace, Key: kv.Key, Value: kv.Value}
+}
+
+// historyIteratorAdapter presents a Fabric 2.x history iterator as a Fabric 1.4 one
+type historyIteratorAdapter struct {
+	iterator servershim.HistoryQueryIteratorInterface
+}
+
+func (i *historyIteratorAdapter) HasNext() bool { return i.iterator.HasNext() }
+func (i *historyIteratorAdapter) Close() error  { return i.iterator.Close() }
+
+func (i *historyIteratorAdapter) Next() (*queryresult.KeyModification, error) {
+	modification, err := i.iterator.Next()
+	if err != nil || modification == nil {
+		return nil, err
+	}
+	return &queryresult.KeyModification{
+		TxId:      modification.TxId,
+		Value:     modification.Value,
+		Timestamp: modification.Timestamp,
+		IsDelete:  modification.IsDelete,
+	}, nil
+}
@@ -0,0 +1,31 @@
+// Package launch starts the token_erc20, multisign and database chaincodes.
+//
+// By default they are built against the Fabric 1.4 shim and started by the
+// peer that launched their container. Built with -tags external, they are
+// served through shim.ChaincodeServer of the Fabric 2.x shim,
+// github.com/hyperledger/fabric-chaincode-go, instead: with
+// CHAINCODE_SERVER_ADDRESS set they run as an external service a peer's
+// external builder connects to, identified by CHAINCODE_ID and secured with the
+// TLS settings below; without it they dial the peer as usual.
+package launch
+
+import (
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// Variables a peer's external builder sets to run chaincode as a service. The
+// TLS key, certificate and client CA certificate are paths to PEM files
+const (
+	IDEnv            = "CHAINCODE_ID"
+	ServerAddressEnv = "CHAINCODE_SERVER_ADDRESS"
+	TLSDisabledEnv   = "CHAINCODE_TLS_DISABLED"
+	TLSKeyEnv        = "CHAINCODE_TLS_KEY"
+	TLSCertEnv       = "CHAINCODE_TLS_CERT"
+	ClientCACertEnv  = "CHAINCODE_CLIENT_CA_CERT"
+)
+
+// Start runs cc, as an external service if CHAINCODE_SERVER_ADDRESS is set, or
+// else under the peer that launched it
+func Start(cc shim.Chaincode) error {
+	return start(cc)
+}
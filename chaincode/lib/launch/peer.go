@@ -0,0 +1,20 @@
+//go:build !external
+// +build !external
+
+package launch
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// start runs cc under the peer that launched it. The Fabric 1.4 shim can only
+// dial out to that peer, so server mode needs a build with -tags external
+func start(cc shim.Chaincode) error {
+	if address := os.Getenv(ServerAddressEnv); address != "" {
+		return fmt.Errorf("%s is set to %s, but this chaincode was built without -tags external and cannot run as a service", ServerAddressEnv, address)
+	}
+	return shim.Start(cc)
+}
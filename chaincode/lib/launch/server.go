@@ -0,0 +1,87 @@
+//go:build external
+// +build external
+
+package launch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	servershim "github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// start serves cc through the Fabric 2.x shim, as an external service if
+// CHAINCODE_SERVER_ADDRESS is set
+func start(cc shim.Chaincode) error {
+	chaincode := &chaincodeAdapter{cc: cc}
+	address := os.Getenv(ServerAddressEnv)
+	if address == "" {
+		return servershim.Start(chaincode)
+	}
+
+	id := os.Getenv(IDEnv)
+	if id == "" {
+		return fmt.Errorf("%s is set, but %s is not", ServerAddressEnv, IDEnv)
+	}
+	tls, err := tlsProperties()
+	if err != nil {
+		return err
+	}
+	server := &servershim.ChaincodeServer{
+		CCID:     id,
+		Address:  address,
+		CC:       chaincode,
+		TLSProps: tls,
+	}
+	return server.Start()
+}
+
+// tlsProperties reads the server's TLS settings. TLS is on unless
+// CHAINCODE_TLS_DISABLED is true; the client CA certificate is optional and,
+// when given, makes the server require client certificates signed by it
+func tlsProperties() (servershim.TLSProperties, error) {
+	disabled := false
+	if value := os.Getenv(TLSDisabledEnv); value != "" {
+		var err error
+		disabled, err = strconv.ParseBool(value)
+		if err != nil {
+			return servershim.TLSProperties{}, fmt.Errorf("Invalid %s: %s", TLSDisabledEnv, err)
+		}
+	}
+	if disabled {
+		return servershim.TLSProperties{Disabled: true}, nil
+	}
+
+	key, err := readPEM(TLSKeyEnv, true)
+	if err != nil {
+		return servershim.TLSProperties{}, err
+	}
+	cert, err := readPEM(TLSCertEnv, true)
+	if err != nil {
+		return servershim.TLSProperties{}, err
+	}
+	clientCACerts, err := readPEM(ClientCACertEnv, false)
+	if err != nil {
+		return servershim.TLSProperties{}, err
+	}
+	return servershim.TLSProperties{Key: key, Cert: cert, ClientCACerts: clientCACerts}, nil
+}
+
+// readPEM reads the file the variable env points to
+func readPEM(env string, required bool) ([]byte, error) {
+	path := os.Getenv(env)
+	if path == "" {
+		if required {
+			return nil, fmt.Errorf("TLS is enabled, but %s is not set. Set it or set %s=true", env, TLSDisabledEnv)
+		}
+		return nil, nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read %s: %s", env, err)
+	}
+	return content, nil
+}
@@ -0,0 +1,76 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// Progress reports what one Apply call did
+type Progress struct {
+	Scanned  int `json:"scanned"`
+	Migrated int `json:"migrated"`
+}
+
+// collectionOf returns the collection an object stored at key belongs to
+func collectionOf(stub shim.ChaincodeStubInterface, collections []Collection, key string) (*Collection, error) {
+	objectType := ""
+	if len(key) > 0 && key[0] == 0x00 {
+		var err error
+		objectType, _, err = stub.SplitCompositeKey(key)
+		if err != nil {
+			return nil, errcode.Newf(errcode.InvalidArgumentCode, "Invalid key %q: %s", key, err)
+		}
+	}
+	for i := range collections {
+		c := &collections[i]
+		switch {
+		case c.Key != "":
+			if key == c.Key {
+				return c, nil
+			}
+		case c.ObjectType != "":
+			if objectType == c.ObjectType {
+				return c, nil
+			}
+		default:
+			if objectType == "" && key >= c.StartKey && (c.EndKey == "" || key < c.EndKey) {
+				return c, nil
+			}
+		}
+	}
+	return nil, errcode.Newf(errcode.InvalidArgumentCode, "Key %q is in no migrated collection", key)
+}
+
+// Apply migrates the objects stored at keys, as listed by Pending. Keys that no
+// longer hold an object, because an earlier call already moved or deleted it,
+// are skipped, so a page can safely be applied twice
+func Apply(stub shim.ChaincodeStubInterface, collections []Collection, keys []string) (*Progress, error) {
+	if len(keys) > MaxPageSize {
+		return nil, errcode.Newf(errcode.InvalidArgumentCode, "Cannot migrate more than %d keys at once", MaxPageSize)
+	}
+	progress := &Progress{}
+	for _, key := range keys {
+		c, err := collectionOf(stub, collections, key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := stub.GetState(key)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get state: %s", err)
+		}
+		if value == nil {
+			continue
+		}
+		progress.Scanned++
+		migrated, err := migrateObject(stub, *c, key, value)
+		if err != nil {
+			return nil, err
+		}
+		if migrated {
+			progress.Migrated++
+		}
+	}
+	return progress, nil
+}
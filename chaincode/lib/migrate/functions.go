@@ -0,0 +1,92 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/chaincode/lib/access"
+	"github.com/chaincode/lib/contract"
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Functions returns the catalogue entries that migrate collections: the
+// read-only MigrationPlan lists a page of keys still to migrate, and Migrate
+// migrates them. Both are for admins only
+func Functions(collections []Collection) []contract.Function {
+	return []contract.Function{
+		{
+			Name:        "MigrationPlan",
+			Description: "Lists a page of stored objects still to upgrade to the current schema. Evaluate it, submit the keys to Migrate, then ask for the next page",
+			Args: []contract.Arg{
+				{Name: "pageSize", Type: "integer", Description: fmt.Sprintf("Objects to read, at most %d", MaxPageSize)},
+				{Name: "bookmark", Type: "string", Optional: true, Description: "Bookmark from the previous page"},
+			},
+			Returns:  "JSON migration plan",
+			ReadOnly: true,
+			Rule:     &access.Admin,
+			Handler:  planHandler(collections),
+		},
+		{
+			Name:        "Migrate",
+			Description: "Upgrades the stored objects a MigrationPlan page listed to the current schema",
+			Args:        []contract.Arg{{Name: "keys", Type: "string", Format: "json", Description: "JSON array of keys from MigrationPlan"}},
+			Returns:     "JSON migration progress",
+			Rule:        &access.Admin,
+			Handler:     applyHandler(collections),
+		},
+	}
+}
+
+// planHandler answers MigrationPlan with Pending
+func planHandler(collections []Collection) contract.Handler {
+	return func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+		if len(args) != 1 && len(args) != 2 {
+			return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1 or 2: page size and optional bookmark")
+		}
+		pageSize, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errcode.InvalidArgument(fmt.Sprintf("Invalid page size: %s", err))
+		}
+		bookmark := ""
+		if len(args) == 2 {
+			bookmark = args[1]
+		}
+
+		plan, err := Pending(stub, collections, bookmark, pageSize)
+		if err != nil {
+			return errcode.FromError(err)
+		}
+		planJSON, err := json.Marshal(plan)
+		if err != nil {
+			return errcode.Internal(fmt.Sprintf("Failed to marshal plan: %s", err))
+		}
+		return shim.Success(planJSON)
+	}
+}
+
+// applyHandler answers Migrate with Apply
+func applyHandler(collections []Collection) contract.Handler {
+	return func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+		if len(args) != 1 {
+			return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1: JSON array of keys")
+		}
+		var keys []string
+		err := json.Unmarshal([]byte(args[0]), &keys)
+		if err != nil {
+			return errcode.InvalidArgument(fmt.Sprintf("Invalid keys, expecting a JSON array of strings: %s", err))
+		}
+
+		progress, err := Apply(stub, collections, keys)
+		if err != nil {
+			return errcode.FromError(err)
+		}
+		progressJSON, err := json.Marshal(progress)
+		if err != nil {
+			return errcode.Internal(fmt.Sprintf("Failed to marshal progress: %s", err))
+		}
+		return shim.Success(progressJSON)
+	}
+}
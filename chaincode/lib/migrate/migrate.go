@@ -0,0 +1,189 @@
+// Package migrate upgrades stored JSON objects to the current schema version
+// in resumable batches, so chaincode can be upgraded without manual data surgery.
+//
+// Every stored object carries a "schemaVersion" field; objects written before
+// versioning was introduced have none and are treated as version 0. Chaincodes
+// declare the version they write for each object type next to its Collection.
+// When a stored shape changes, bump that version and append the upgrade to the
+// collection's Upgrades, so the two stay equal.
+//
+// Fabric cannot resume a composite key range mid-way, and only allows paginated
+// queries in read-only transactions, so a migration runs in two steps: Pending
+// lists a page of keys still to migrate, and Apply migrates the keys it listed.
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// VersionField is the JSON field holding an object's schema version
+const VersionField = "schemaVersion"
+
+// Upgrade moves a decoded object from one schema version to the next, in place
+type Upgrade func(obj map[string]interface{}) error
+
+// Stamp is the upgrade from unversioned records to version 1. It changes nothing
+// but the version field, which Apply sets after every upgrade
+func Stamp(obj map[string]interface{}) error {
+	return nil
+}
+
+// Collection is one kind of stored object. Objects live either under a single
+// Key, under the composite key ObjectType, or in the simple key range
+// [StartKey, EndKey). Upgrades[i] moves an object from version i to i+1, so the
+// current version is len(Upgrades). Rekey, if set, returns the key an object
+// moves to; Apply stores it there and deletes the old key. Index, if set,
+// rebuilds the secondary index entries of an object, and Indexed reports
+// whether they are all present; objects missing some are migrated even when
+// current. Without Indexed, every object is reindexed on every run
+type Collection struct {
+	Name       string
+	Key        string
+	ObjectType string
+	StartKey   string
+	EndKey     string
+	Upgrades   []Upgrade
+	Rekey      func(stub shim.ChaincodeStubInterface, key string) (string, error)
+	Index      func(stub shim.ChaincodeStubInterface, key string, value []byte) error
+	Indexed    func(stub shim.ChaincodeStubInterface, key string, value []byte) (bool, error)
+}
+
+// Version returns the current schema version of the collection
+func (c Collection) Version() int {
+	return len(c.Upgrades)
+}
+
+// upgrade decodes value, applies the pending upgrades and returns the new
+// encoding, or nil if the object is already current
+func upgrade(c Collection, key string, value []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	var obj map[string]interface{}
+	err := decoder.Decode(&obj)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode %s: %s", key, err)
+	}
+
+	version := 0
+	if v, ok := obj[VersionField].(json.Number); ok {
+		n, err := v.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("Invalid schema version in %s: %s", key, err)
+		}
+		version = int(n)
+	}
+	if version > c.Version() {
+		return nil, fmt.Errorf("%s has schema version %d, newer than this chaincode's %d", key, version, c.Version())
+	}
+	if version == c.Version() {
+		return nil, nil
+	}
+
+	for ; version < c.Version(); version++ {
+		err = c.Upgrades[version](obj)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to upgrade %s from version %d: %s", key, version, err)
+		}
+		obj[VersionField] = version + 1
+	}
+	return json.Marshal(obj)
+}
+
+// rekey returns the key an object of c moves to, and deletes it from its old key.
+// An object already at the new key is never overwritten
+func rekey(stub shim.ChaincodeStubInterface, c Collection, key string) (string, error) {
+	newKey, err := c.Rekey(stub, key)
+	if err != nil {
+		return "", fmt.Errorf("Failed to rekey %s: %s", key, err)
+	}
+	if newKey == key {
+		return key, nil
+	}
+	existing, err := stub.GetState(newKey)
+	if err != nil {
+		return "", fmt.Errorf("Failed to get state: %s", err)
+	}
+	if existing != nil {
+		return "", errcode.Newf(errcode.ConflictCode, "Cannot move %s: its new key is already taken", key)
+	}
+	err = stub.DelState(key)
+	if err != nil {
+		return "", fmt.Errorf("Failed to delete state: %s", err)
+	}
+	return newKey, nil
+}
+
+// index rebuilds the secondary index entries of an object of c, if it has any
+func index(stub shim.ChaincodeStubInterface, c Collection, key string, value []byte) error {
+	if c.Index == nil {
+		return nil
+	}
+	err := c.Index(stub, key, value)
+	if err != nil {
+		return fmt.Errorf("Failed to index %s: %s", key, err)
+	}
+	return nil
+}
+
+// pending reports whether the object at key still has to be migrated: it is
+// behind the current version, has to move, or is missing index entries
+func pending(stub shim.ChaincodeStubInterface, c Collection, key string, value []byte) (bool, error) {
+	if c.Index != nil {
+		if c.Indexed == nil {
+			return true, nil
+		}
+		indexed, err := c.Indexed(stub, key, value)
+		if err != nil {
+			return false, fmt.Errorf("Failed to check the index of %s: %s", key, err)
+		}
+		if !indexed {
+			return true, nil
+		}
+	}
+	if c.Rekey != nil {
+		newKey, err := c.Rekey(stub, key)
+		if err != nil {
+			return false, fmt.Errorf("Failed to rekey %s: %s", key, err)
+		}
+		if newKey != key {
+			return true, nil
+		}
+	}
+	upgraded, err := upgrade(c, key, value)
+	if err != nil {
+		return false, err
+	}
+	return upgraded != nil, nil
+}
+
+// migrateObject upgrades, moves and reindexes the object of c stored at key,
+// and reports whether it wrote a new value
+func migrateObject(stub shim.ChaincodeStubInterface, c Collection, key string, value []byte) (bool, error) {
+	upgraded, err := upgrade(c, key, value)
+	if err != nil {
+		return false, err
+	}
+	newKey := key
+	if c.Rekey != nil {
+		newKey, err = rekey(stub, c, key)
+		if err != nil {
+			return false, err
+		}
+	}
+	if upgraded == nil && newKey == key {
+		return false, index(stub, c, key, value)
+	}
+	if upgraded == nil {
+		upgraded = value
+	}
+	err = stub.PutState(newKey, upgraded)
+	if err != nil {
+		return false, fmt.Errorf("Failed to put state: %s", err)
+	}
+	return true, index(stub, c, newKey, upgraded)
+}
@@ -0,0 +1,342 @@
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// pagedStub adds the paginated queries the mock stub lacks. Its bookmark is
+// the last key of the previous page
+type pagedStub struct {
+	*shim.MockStub
+}
+
+type sliceIterator struct {
+	kvs []*queryresult.KV
+}
+
+func (i *sliceIterator) HasNext() bool { return len(i.kvs) > 0 }
+func (i *sliceIterator) Close() error  { return nil }
+
+func (i *sliceIterator) Next() (*queryresult.KV, error) {
+	kv := i.kvs[0]
+	i.kvs = i.kvs[1:]
+	return kv, nil
+}
+
+func paginate(iterator shim.StateQueryIteratorInterface, err error, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if err != nil {
+		return nil, nil, err
+	}
+	defer iterator.Close()
+	page := &sliceIterator{}
+	for iterator.HasNext() && int32(len(page.kvs)) < pageSize {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if bookmark == "" || kv.Key > bookmark {
+			page.kvs = append(page.kvs, kv)
+		}
+	}
+	metadata := &pb.QueryResponseMetadata{FetchedRecordsCount: int32(len(page.kvs))}
+	if len(page.kvs) > 0 {
+		metadata.Bookmark = page.kvs[len(page.kvs)-1].Key
+	}
+	return page, metadata, nil
+}
+
+func (s *pagedStub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	iterator, err := s.GetStateByRange(startKey, endKey)
+	return paginate(iterator, err, pageSize, bookmark)
+}
+
+func (s *pagedStub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	iterator, err := s.GetStateByPartialCompositeKey(objectType, keys)
+	return paginate(iterator, err, pageSize, bookmark)
+}
+
+func newStub(t *testing.T, state map[string]string) *pagedStub {
+	t.Helper()
+	stub := &pagedStub{shim.NewMockStub("migrate", nil)}
+	stub.MockTransactionStart("tx")
+	for key, value := range state {
+		if err := stub.PutState(key, []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return stub
+}
+
+func addNote(obj map[string]interface{}) error {
+	obj["note"] = "upgraded"
+	return nil
+}
+
+func TestUpgrade(t *testing.T) {
+	c := Collection{Name: "item", Upgrades: []Upgrade{Stamp, addNote}}
+	failing := Collection{Name: "item", Upgrades: []Upgrade{func(map[string]interface{}) error { return errors.New("boom") }}}
+	tests := []struct {
+		name    string
+		c       Collection
+		value   string
+		want    string
+		wantErr string
+	}{
+		{name: "unversioned", c: c, value: `{"a":1}`, want: `{"a":1,"note":"upgraded","schemaVersion":2}`},
+		{name: "part way", c: c, value: `{"a":1,"schemaVersion":1}`, want: `{"a":1,"note":"upgraded","schemaVersion":2}`},
+		{name: "current", c: c, value: `{"a":1,"schemaVersion":2}`},
+		{name: "large numbers survive", c: c, value: `{"amount":18446744073709551615,"schemaVersion":1}`, want: `{"amount":18446744073709551615,"note":"upgraded","schemaVersion":2}`},
+		{name: "newer than the chaincode", c: c, value: `{"schemaVersion":3}`, wantErr: "newer than this chaincode's 2"},
+		{name: "not an object", c: c, value: `[1]`, wantErr: "Failed to decode"},
+		{name: "failing upgrade", c: failing, value: `{}`, wantErr: "boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := upgrade(tt.c, "k", []byte(tt.value))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("upgrade() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("upgrade() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("upgrade() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectionOf(t *testing.T) {
+	stub := newStub(t, nil)
+	item, _ := stub.CreateCompositeKey("item", []string{"1"})
+	other, _ := stub.CreateCompositeKey("other", []string{"1"})
+	collections := []Collection{
+		{Name: "token", Key: "token"},
+		{Name: "item", ObjectType: "item"},
+		{Name: "people", StartKey: "p", EndKey: "q"},
+	}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"token", "token"},
+		{item, "item"},
+		{"pat", "people"},
+		{"q", ""},
+		{"tokens", ""},
+		{other, ""},
+	}
+	for _, tt := range tests {
+		c, err := collectionOf(stub, collections, tt.key)
+		got := ""
+		if err == nil {
+			got = c.Name
+		}
+		if got != tt.want {
+			t.Errorf("collectionOf(%q) = %q (%v), want %q", tt.key, got, err, tt.want)
+		}
+	}
+}
+
+// migrateAll plans and applies pages until the plan is done, and returns the
+// keys it migrated
+func migrateAll(t *testing.T, stub shim.ChaincodeStubInterface, collections []Collection, pageSize int) []string {
+	t.Helper()
+	var migrated []string
+	bookmark := ""
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatal("migration does not finish")
+		}
+		plan, err := Pending(stub, collections, bookmark, pageSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(plan.Keys) > pageSize {
+			t.Fatalf("plan lists %d keys, more than the page size %d", len(plan.Keys), pageSize)
+		}
+		_, err = Apply(stub, collections, plan.Keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		migrated = append(migrated, plan.Keys...)
+		if plan.Done {
+			return migrated
+		}
+		bookmark = plan.Bookmark
+	}
+}
+
+func TestPendingAndApply(t *testing.T) {
+	stub := newStub(t, nil)
+	itemKey := func(id string) string {
+		key, _ := stub.CreateCompositeKey("item", []string{id})
+		return key
+	}
+	state := map[string]string{
+		"config":     `{"fee":1}`,
+		"a":          `{"id":"a"}`,
+		"b":          `{"id":"b","schemaVersion":1}`,
+		itemKey("c"): `{"id":"c","schemaVersion":2}`,
+		itemKey("d"): `{"id":"d"}`,
+		itemKey("e"): `{"id":"e","schemaVersion":2}`,
+	}
+	for key, value := range state {
+		if err := stub.PutState(key, []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	items := []Upgrade{Stamp, addNote}
+	collections := []Collection{
+		{Name: "config", Key: "config", Upgrades: []Upgrade{Stamp}},
+		{Name: "legacy", StartKey: "a", EndKey: "c", Upgrades: items, Rekey: func(stub shim.ChaincodeStubInterface, key string) (string, error) {
+			return stub.CreateCompositeKey("item", []string{key})
+		}},
+		{Name: "item", ObjectType: "item", Upgrades: items},
+	}
+
+	migrated := migrateAll(t, stub, collections, 2)
+	sort.Strings(migrated)
+	want := []string{"a", "b", "config", itemKey("d")}
+	sort.Strings(want)
+	if strings.Join(migrated, ",") != strings.Join(want, ",") {
+		t.Errorf("migrated %q, want %q", migrated, want)
+	}
+
+	for _, key := range []string{"a", "b"} {
+		if value, _ := stub.GetState(key); value != nil {
+			t.Errorf("legacy key %s was not moved", key)
+		}
+	}
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		value, _ := stub.GetState(itemKey(id))
+		var obj map[string]interface{}
+		if err := json.Unmarshal(value, &obj); err != nil {
+			t.Fatalf("item %s: %v", id, err)
+		}
+		if obj[VersionField] != float64(2) {
+			t.Errorf("item %s has schema version %v, want 2", id, obj[VersionField])
+		}
+	}
+
+	// A second run finds nothing left to do
+	if again := migrateAll(t, stub, collections, 2); len(again) != 0 {
+		t.Errorf("second run migrated %q", again)
+	}
+}
+
+func TestIndexVisitsEveryObject(t *testing.T) {
+	stub := newStub(t, nil)
+	for _, id := range []string{"1", "2", "3"} {
+		key, _ := stub.CreateCompositeKey("item", []string{id})
+		if err := stub.PutState(key, []byte(`{"schemaVersion":1}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	indexed := map[string]bool{}
+	collections := []Collection{{Name: "item", ObjectType: "item", Upgrades: []Upgrade{Stamp}, Index: func(stub shim.ChaincodeStubInterface, key string, value []byte) error {
+		indexed[key] = true
+		return nil
+	}}}
+
+	migrated := migrateAll(t, stub, collections, 2)
+	if len(migrated) != 3 || len(indexed) != 3 {
+		t.Errorf("listed %d and indexed %d objects, want 3 and 3", len(migrated), len(indexed))
+	}
+}
+
+func TestIndexedObjectsAreSkipped(t *testing.T) {
+	stub := newStub(t, nil)
+	for _, id := range []string{"1", "2", "3"} {
+		key, _ := stub.CreateCompositeKey("item", []string{id})
+		if err := stub.PutState(key, []byte(`{"schemaVersion":1}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	indexKey := func(key string) string { return "index" + key }
+	if err := stub.PutState(indexKey(mustKey(t, stub, "2")), []byte{0}); err != nil {
+		t.Fatal(err)
+	}
+	collections := []Collection{{
+		Name:       "item",
+		ObjectType: "item",
+		Upgrades:   []Upgrade{Stamp},
+		Index: func(stub shim.ChaincodeStubInterface, key string, value []byte) error {
+			return stub.PutState(indexKey(key), []byte{0})
+		},
+		Indexed: func(stub shim.ChaincodeStubInterface, key string, value []byte) (bool, error) {
+			entry, err := stub.GetState(indexKey(key))
+			return entry != nil, err
+		},
+	}}
+
+	migrated := migrateAll(t, stub, collections, 2)
+	want := []string{mustKey(t, stub, "1"), mustKey(t, stub, "3")}
+	if strings.Join(migrated, ",") != strings.Join(want, ",") {
+		t.Errorf("migrated %q, want %q", migrated, want)
+	}
+	if again := migrateAll(t, stub, collections, 2); len(again) != 0 {
+		t.Errorf("second run migrated %q", again)
+	}
+}
+
+func mustKey(t *testing.T, stub shim.ChaincodeStubInterface, id string) string {
+	t.Helper()
+	key, err := stub.CreateCompositeKey("item", []string{id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestPendingArguments(t *testing.T) {
+	stub := newStub(t, nil)
+	collections := []Collection{{Name: "config", Key: "config", Upgrades: []Upgrade{Stamp}}}
+	tests := []struct {
+		name     string
+		bookmark string
+		pageSize int
+		wantErr  bool
+	}{
+		{name: "first page", pageSize: 1},
+		{name: "largest page", pageSize: MaxPageSize},
+		{name: "zero page size", pageSize: 0, wantErr: true},
+		{name: "page too large", pageSize: MaxPageSize + 1, wantErr: true},
+		{name: "bad bookmark", bookmark: "???", pageSize: 1, wantErr: true},
+		{name: "past the end", bookmark: encodeBookmark(position{Collection: 5}), pageSize: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Pending(stub, collections, tt.bookmark, tt.pageSize)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Pending() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyTwice(t *testing.T) {
+	stub := newStub(t, map[string]string{"config": `{}`})
+	collections := []Collection{{Name: "config", Key: "config", Upgrades: []Upgrade{Stamp}}}
+	progress, err := Apply(stub, collections, []string{"config", "config"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Scanned != 2 || progress.Migrated != 1 {
+		t.Errorf("Apply() = %+v, want 2 scanned and 1 migrated", progress)
+	}
+	if _, err := Apply(stub, collections, []string{"unknown"}); err == nil {
+		t.Error("Apply() accepted a key outside every collection")
+	}
+}
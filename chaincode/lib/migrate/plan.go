@@ -0,0 +1,153 @@
+package migrate
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// MaxPageSize bounds the objects one Plan call reads and the keys one Apply
+// call migrates
+const MaxPageSize = 1000
+
+// Plan is one page of the keys still to migrate. A page covers at most one
+// collection and may list no keys at all; pass Bookmark to the next call to
+// continue until Done is set
+type Plan struct {
+	Collection string   `json:"collection"`
+	Scanned    int      `json:"scanned"`
+	Keys       []string `json:"keys"`
+	Bookmark   string   `json:"bookmark"`
+	Done       bool     `json:"done"`
+}
+
+// position is the decoded form of a plan bookmark: the collection reached and
+// Fabric's bookmark within it
+type position struct {
+	Collection int    `json:"c"`
+	Page       string `json:"p,omitempty"`
+}
+
+func decodeBookmark(bookmark string) (position, error) {
+	var pos position
+	if bookmark == "" {
+		return pos, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(bookmark)
+	if err != nil {
+		return pos, errcode.Newf(errcode.InvalidArgumentCode, "Invalid bookmark: %s", err)
+	}
+	err = json.Unmarshal(raw, &pos)
+	if err != nil {
+		return pos, errcode.Newf(errcode.InvalidArgumentCode, "Invalid bookmark: %s", err)
+	}
+	return pos, nil
+}
+
+func encodeBookmark(pos position) string {
+	raw, _ := json.Marshal(pos)
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+// page reads up to pageSize objects of a range collection, starting at Fabric's
+// bookmark, and returns the next bookmark, empty once the range is exhausted
+func page(stub shim.ChaincodeStubInterface, c Collection, pageSize int, bookmark string) (shim.StateQueryIteratorInterface, string, error) {
+	var iterator shim.StateQueryIteratorInterface
+	var metadata *pb.QueryResponseMetadata
+	var err error
+	if c.ObjectType != "" {
+		iterator, metadata, err = stub.GetStateByPartialCompositeKeyWithPagination(c.ObjectType, []string{}, int32(pageSize), bookmark)
+	} else {
+		iterator, metadata, err = stub.GetStateByRangeWithPagination(c.StartKey, c.EndKey, int32(pageSize), bookmark)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to query %s: %s", c.Name, err)
+	}
+	next := ""
+	if metadata != nil && int(metadata.FetchedRecordsCount) == pageSize {
+		next = metadata.Bookmark
+	}
+	return iterator, next, nil
+}
+
+// Pending lists the keys of up to pageSize objects, starting at bookmark, that
+// are behind their collection's schema version, have to move, or have index
+// entries to rebuild. Each call resumes where the last one stopped, so a full
+// plan reads every object once. It runs paginated queries, which Fabric only
+// allows in read-only transactions: evaluate it, then submit the keys to Apply.
+// Apply each page before asking for the next, so objects Apply moves to a later
+// collection are listed again there
+func Pending(stub shim.ChaincodeStubInterface, collections []Collection, bookmark string, pageSize int) (*Plan, error) {
+	if pageSize <= 0 || pageSize > MaxPageSize {
+		return nil, errcode.Newf(errcode.InvalidArgumentCode, "Page size must be between 1 and %d", MaxPageSize)
+	}
+	pos, err := decodeBookmark(bookmark)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{Keys: []string{}}
+	if pos.Collection >= len(collections) {
+		plan.Done = true
+		return plan, nil
+	}
+
+	c := collections[pos.Collection]
+	plan.Collection = c.Name
+	next := ""
+	if c.Key != "" {
+		value, err := stub.GetState(c.Key)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get state: %s", err)
+		}
+		if value != nil {
+			plan.Scanned++
+			due, err := pending(stub, c, c.Key, value)
+			if err != nil {
+				return nil, err
+			}
+			if due {
+				plan.Keys = append(plan.Keys, c.Key)
+			}
+		}
+	} else {
+		var iterator shim.StateQueryIteratorInterface
+		iterator, next, err = page(stub, c, pageSize, pos.Page)
+		if err != nil {
+			return nil, err
+		}
+		defer iterator.Close()
+		for iterator.HasNext() {
+			kv, err := iterator.Next()
+			if err != nil {
+				return nil, err
+			}
+			// Simple key ranges never include composite keys on a peer; skip them defensively
+			if c.ObjectType == "" && len(kv.Key) > 0 && kv.Key[0] == 0x00 {
+				continue
+			}
+			plan.Scanned++
+			due, err := pending(stub, c, kv.Key, kv.Value)
+			if err != nil {
+				return nil, err
+			}
+			if due {
+				plan.Keys = append(plan.Keys, kv.Key)
+			}
+		}
+	}
+
+	if next != "" {
+		pos.Page = next
+	} else {
+		pos = position{Collection: pos.Collection + 1}
+	}
+	plan.Done = pos.Collection >= len(collections)
+	if !plan.Done {
+		plan.Bookmark = encodeBookmark(pos)
+	}
+	return plan, nil
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/chaincode/lib/launch"
+	"github.com/chaincode/local_token/mytoken/go/token"
+)
+
+func main() {
+	err := launch.Start(new(token.TokenERC20Chaincode))
+	if err != nil {
+		fmt.Printf("Error starting TokenERC20Chaincode: %s", err)
+	}
+}
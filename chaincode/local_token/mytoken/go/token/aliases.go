@@ -0,0 +1,261 @@
+package token
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/chaincode/lib/access"
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/lib/cid"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Composite key object types for the alias registry
+const (
+	aliasPrefix        = "alias"
+	accountAliasPrefix = "account~alias"
+)
+
+// aliasPattern accepts "empuser8"-style names and "user8@orgstaff". Underscores are
+// excluded because they separate owner and spender in allowance keys, and the
+// length cap keeps aliases distinct from hex-encoded identities
+var aliasPattern = regexp.MustCompile(`^[a-z][a-z0-9.-]{2,31}(@[a-z0-9-]{2,31})?$`)
+
+// AliasRecord binds a human-readable alias to an account
+type AliasRecord struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Alias         string `json:"alias"`
+	Account       string `json:"account"`
+	RegisteredAt  int64  `json:"registeredAt"`
+}
+
+// isAlias reports whether s has the shape of an alias rather than an account address
+func isAlias(s string) bool {
+	return aliasPattern.MatchString(strings.ToLower(s))
+}
+
+func getAliasRecord(stub shim.ChaincodeStubInterface, alias string) (*AliasRecord, error) {
+	key, err := stub.CreateCompositeKey(aliasPrefix, []string{strings.ToLower(alias)})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create key: %s", err)
+	}
+	recordJSON, err := stub.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get alias: %s", err)
+	}
+	if recordJSON == nil {
+		return nil, nil
+	}
+	var record AliasRecord
+	err = json.Unmarshal(recordJSON, &record)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal alias: %s", err)
+	}
+	return &record, nil
+}
+
+// resolveAccount turns an alias into the account it is registered to.
+// Anything that does not look like an alias is returned unchanged as an address
+func resolveAccount(stub shim.ChaincodeStubInterface, account string) (string, error) {
+	if account == "" {
+		return "", errcode.New(errcode.InvalidArgumentCode, "Account must not be empty")
+	}
+	if !isAlias(account) {
+		return account, nil
+	}
+	record, err := getAliasRecord(stub, account)
+	if err != nil {
+		return "", err
+	}
+	if record == nil {
+		return "", errcode.Newf(errcode.NotFoundCode, "Unknown alias: %s", account)
+	}
+	return record.Account, nil
+}
+
+// reservedAliases name system accounts. Only administrators can claim them
+var reservedAliases = map[string]bool{
+	"admin":     true,
+	"auditor":   true,
+	"escrow":    true,
+	"fees":      true,
+	"governor":  true,
+	"multisign": true,
+	"payroll":   true,
+	"system":    true,
+	"token":     true,
+	"treasury":  true,
+}
+
+// checkAliasClaim guards against squatting on names that identify somebody else.
+// Every alias names the caller: "name@org" must match the caller's enrollment
+// ID and MSP. "emp<name>" is the short form for employees, so only members of
+// OrgStaff can claim it, for their own enrollment ID; cryptogen issues the same
+// user names in every org. Reserved system names can only be claimed by
+// administrators
+func checkAliasClaim(stub shim.ChaincodeStubInterface, alias string) error {
+	id, err := access.GetIdentity(stub)
+	if err != nil {
+		return err
+	}
+	if reservedAliases[alias] {
+		allowed, err := access.Admin.Allows(id)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return errcode.Newf(errcode.ForbiddenCode, "Alias %s is reserved for a system account", alias)
+		}
+		return nil
+	}
+
+	cert, err := cid.GetX509Certificate(stub)
+	if err != nil {
+		return fmt.Errorf("Failed to get caller certificate: %s", err)
+	}
+	// Fabric CA enrollment CNs are the bare enrollment ID; cryptogen ones look
+	// like User8@orgStaff.example.com
+	userName := strings.ToLower(cert.Subject.CommonName)
+	if at := strings.Index(userName, "@"); at >= 0 {
+		userName = userName[:at]
+	}
+	orgName := strings.ToLower(strings.TrimSuffix(id.MSPID, "MSP"))
+
+	if at := strings.Index(alias, "@"); at >= 0 {
+		if alias[:at] != userName || alias[at+1:] != orgName {
+			return errcode.Newf(errcode.ForbiddenCode, "Alias %s does not match the caller's identity %s@%s", alias, userName, orgName)
+		}
+		return nil
+	}
+	if id.MSPID != access.StaffMSP {
+		return errcode.Newf(errcode.ForbiddenCode, "Alias %s does not name the caller. Expecting %s@%s; emp aliases are for members of %s", alias, userName, orgName, access.StaffMSP)
+	}
+	if alias != "emp"+userName {
+		return errcode.Newf(errcode.ForbiddenCode, "Alias %s does not name the caller. Expecting %s@%s or emp%s", alias, userName, orgName, userName)
+	}
+	return nil
+}
+
+// RegisterAlias registers a human-readable alias for the caller's own account.
+// Each account can hold one alias and each alias can be held by one account
+func (t *TokenERC20Chaincode) RegisterAlias(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1: alias")
+	}
+
+	alias := strings.ToLower(args[0])
+	if !aliasPattern.MatchString(alias) {
+		return errcode.InvalidArgument("Invalid alias. Expecting 3-32 lowercase letters, digits, '.' or '-', optionally followed by @org")
+	}
+
+	caller, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
+	}
+	callerHex := hex.EncodeToString(caller)
+
+	existing, err := getAliasRecord(stub, alias)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	if existing != nil {
+		return errcode.Conflict(fmt.Sprintf("Alias is already registered: %s", alias))
+	}
+
+	reverseKey, err := stub.CreateCompositeKey(accountAliasPrefix, []string{callerHex})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to create key: %s", err))
+	}
+	current, err := stub.GetState(reverseKey)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get alias: %s", err))
+	}
+	if current != nil {
+		return errcode.Conflict(fmt.Sprintf("Account already has alias %s, release it first", string(current)))
+	}
+
+	err = checkAliasClaim(stub, alias)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	now, err := txTime(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	record := AliasRecord{SchemaVersion: aliasVersion, Alias: alias, Account: callerHex, RegisteredAt: now}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to marshal alias: %s", err))
+	}
+	aliasKey, err := stub.CreateCompositeKey(aliasPrefix, []string{alias})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to create key: %s", err))
+	}
+	err = stub.PutState(aliasKey, recordJSON)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to put state: %s", err))
+	}
+	err = stub.PutState(reverseKey, []byte(alias))
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to put state: %s", err))
+	}
+
+	return shim.Success(nil)
+}
+
+// ReleaseAlias removes the caller's alias so it can be registered again
+func (t *TokenERC20Chaincode) ReleaseAlias(stub shim.ChaincodeStubInterface) pb.Response {
+	caller, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
+	}
+
+	reverseKey, err := stub.CreateCompositeKey(accountAliasPrefix, []string{hex.EncodeToString(caller)})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to create key: %s", err))
+	}
+	alias, err := stub.GetState(reverseKey)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get alias: %s", err))
+	}
+	if alias == nil {
+		return errcode.NotFound("Account has no alias")
+	}
+
+	aliasKey, err := stub.CreateCompositeKey(aliasPrefix, []string{string(alias)})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to create key: %s", err))
+	}
+	err = stub.DelState(aliasKey)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to delete state: %s", err))
+	}
+	err = stub.DelState(reverseKey)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to delete state: %s", err))
+	}
+
+	return shim.Success(nil)
+}
+
+// ResolveAlias returns the account an alias is registered to
+func (t *TokenERC20Chaincode) ResolveAlias(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1: alias")
+	}
+
+	record, err := getAliasRecord(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	if record == nil {
+		return errcode.NotFound(fmt.Sprintf("Unknown alias: %s", args[0]))
+	}
+
+	return shim.Success([]byte(record.Account))
+}
@@ -0,0 +1,368 @@
+package token
+
+import (
+	"github.com/chaincode/lib/access"
+	"github.com/chaincode/lib/contract"
+	"github.com/chaincode/lib/migrate"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Rules for restricted functions. The accountant org holds the treasury,
+// the manager org governs disputes and rates. Minting can also be approved
+// by the board as a multisign proposal
+var (
+	accountantRule = access.Rule{MSPIDs: []string{access.AccountantMSP}}
+	managerRule    = access.Rule{MSPIDs: []string{access.ManagerMSP}}
+	mintRule       = accountantRule.OrGovernedBy(access.Board)
+)
+
+// Arguments shared by several functions
+var (
+	amountArg    = contract.Arg{Name: "amount", Type: "integer", Description: "Amount in the token's smallest unit"}
+	memoArg      = contract.Arg{Name: "memo", Type: "string", Optional: true, Description: "Free-text memo, at most 256 bytes"}
+	referenceArg = contract.Arg{Name: "reference", Type: "string", Format: "json", Optional: true, Description: "JSON object with invoiceId, costCentre and/or payrollRun"}
+	pageSizeArg  = contract.Arg{Name: "pageSize", Type: "integer", Optional: true, Description: "Records per page, 1 to 1000, default 100"}
+	bookmarkArg  = contract.Arg{Name: "bookmark", Type: "string", Optional: true, Description: "Bookmark from the previous page"}
+)
+
+func accountArg(name, description string) contract.Arg {
+	return contract.Arg{Name: name, Type: "string", Format: "account", Description: description}
+}
+
+// withoutArgs adapts a function that takes no arguments to a contract.Handler
+func withoutArgs(f func(shim.ChaincodeStubInterface) pb.Response) contract.Handler {
+	return func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+		return f(stub)
+	}
+}
+
+// idempotent wraps a handler with withIdempotency under the function's name
+func idempotent(function string, handler contract.Handler) contract.Handler {
+	return func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+		return withIdempotency(stub, function, args, handler)
+	}
+}
+
+// catalogue is the function catalogue of the token chaincode. Invoke dispatches
+// through it and GetMetadata publishes it
+func (t *TokenERC20Chaincode) catalogue() *contract.Contract {
+	return &contract.Contract{
+		Name:        "token_erc20",
+		Description: "ERC20-style token. Accounts are hex-encoded serialized identities or registered aliases",
+		Functions: append([]contract.Function{
+			{
+				Name:        "Initialize",
+				Description: "Creates the token and credits the whole supply to the caller",
+				Args: []contract.Arg{
+					{Name: "name", Type: "string"},
+					{Name: "symbol", Type: "string"},
+					{Name: "totalSupply", Type: "integer"},
+					{Name: "decimals", Type: "integer"},
+				},
+				Returns: "nothing",
+				Rule:    &accountantRule,
+				Handler: t.Initialize,
+			},
+			{
+				Name:        "Mint",
+				Description: "Creates new tokens in the caller's account. Idempotent per idempotencyKey transient field. Emits Transfer",
+				Args:        []contract.Arg{amountArg},
+				Returns:     "nothing",
+				Rule:        &mintRule,
+				Handler:     idempotent("Mint", t.Mint),
+			},
+			{
+				Name:        "ClientAccountBalance",
+				Description: "Returns the caller's balance, opening the account if needed",
+				Returns:     "balance as a decimal string",
+				Handler:     withoutArgs(t.ClientAccountBalance),
+			},
+			{
+				Name:        "ClientAccountID",
+				Description: "Returns the caller's account ID",
+				Returns:     "account ID",
+				ReadOnly:    true,
+				Handler:     withoutArgs(t.ClientAccountID),
+			},
+			{
+				Name:        "transfer",
+				Description: "Moves tokens from the caller to another account. Idempotent per idempotencyKey transient field. Emits Transfer",
+				Args:        []contract.Arg{accountArg("to", "Recipient"), amountArg, memoArg, referenceArg},
+				Returns:     "transfer ID",
+				Handler:     idempotent("transfer", t.Transfer),
+			},
+			{
+				Name:        "Approve",
+				Description: "Allows a spender to transfer up to amount from the caller's account",
+				Args:        []contract.Arg{accountArg("spender", "Spender"), amountArg},
+				Returns:     "nothing",
+				Handler:     t.Approve,
+			},
+			{
+				Name:        "Allowance",
+				Description: "Returns how much spender may still transfer from owner",
+				Args:        []contract.Arg{accountArg("owner", "Owner"), accountArg("spender", "Spender")},
+				Returns:     "allowance as a decimal string",
+				ReadOnly:    true,
+				Handler:     t.Allowance,
+			},
+			{
+				Name:        "transferFrom",
+				Description: "Moves tokens between accounts using the caller's allowance. Idempotent per idempotencyKey transient field. Emits Transfer",
+				Args:        []contract.Arg{accountArg("from", "Owner"), accountArg("to", "Recipient"), amountArg, memoArg, referenceArg},
+				Returns:     "transfer ID",
+				Handler:     idempotent("transferFrom", t.TransferFrom),
+			},
+			{
+				Name:        "balanceOf",
+				Description: "Returns the balance of an account",
+				Args:        []contract.Arg{accountArg("account", "Account")},
+				Returns:     "balance as a decimal string",
+				ReadOnly:    true,
+				Handler:     t.BalanceOf,
+			},
+			{
+				Name:     "name",
+				Returns:  "token name",
+				ReadOnly: true,
+				Handler:  withoutArgs(t.Name),
+			},
+			{
+				Name:     "symbol",
+				Returns:  "token symbol",
+				ReadOnly: true,
+				Handler:  withoutArgs(t.Symbol),
+			},
+			{
+				Name:     "totalSupply",
+				Returns:  "total supply as a decimal string",
+				ReadOnly: true,
+				Handler:  withoutArgs(t.TotalSupply),
+			},
+			{
+				Name:        "CreateStandingOrder",
+				Description: "Authorises recurring payments from the caller",
+				Args: []contract.Arg{
+					accountArg("to", "Recipient"),
+					amountArg,
+					{Name: "interval", Type: "integer", Description: "Seconds between payments, at most a year"},
+					{Name: "startTime", Type: "integer", Format: "unix-time", Description: "Not before the transaction time"},
+					{Name: "endTime", Type: "integer", Format: "unix-time", Description: "0 for no end"},
+				},
+				Returns: "order ID",
+				Handler: t.CreateStandingOrder,
+			},
+			{
+				Name:        "CancelStandingOrder",
+				Description: "Stops future payments of one of the caller's orders",
+				Args:        []contract.Arg{{Name: "orderId", Type: "string"}},
+				Returns:     "nothing",
+				Handler:     t.CancelStandingOrder,
+			},
+			{
+				Name:        "GetStandingOrder",
+				Description: "Returns a standing order with its payment and failure history",
+				Args:        []contract.Arg{{Name: "orderId", Type: "string"}},
+				Returns:     "JSON StandingOrder",
+				ReadOnly:    true,
+				Handler:     t.GetStandingOrder,
+			},
+			{
+				Name:        "ExecuteDueOrders",
+				Description: "Makes one payment for every due standing order. Emits StandingOrdersExecuted",
+				Returns:     "JSON execution summary",
+				Handler:     withoutArgs(t.ExecuteDueOrders),
+			},
+			{
+				Name:        "GetTransfer",
+				Description: "Returns a transfer record with its refunds and dispute",
+				Args:        []contract.Arg{{Name: "transferId", Type: "string"}},
+				Returns:     "JSON TransferRecord",
+				ReadOnly:    true,
+				Handler:     t.GetTransfer,
+			},
+			{
+				Name:        "GetStatement",
+				Description: "Returns the transfers to and from an account",
+				Args:        []contract.Arg{accountArg("account", "Account"), {Name: "search", Type: "string", Optional: true, Description: "Text to find in memos and references"}},
+				Returns:     "JSON array of TransferRecord",
+				ReadOnly:    true,
+				Handler:     t.GetStatement,
+			},
+			{
+				Name:        "Refund",
+				Description: "Returns part or all of a transfer the caller received. Emits Refund",
+				Args:        []contract.Arg{{Name: "transferId", Type: "string"}, amountArg},
+				Returns:     "nothing",
+				Handler:     t.Refund,
+			},
+			{
+				Name:        "OpenDispute",
+				Description: "Disputes a transfer the caller sent, within the dispute window. The amount not yet refunded, or as much of it as the recipient still holds, is held in escrow until the dispute is resolved. Emits DisputeOpened",
+				Args:        []contract.Arg{{Name: "transferId", Type: "string"}, {Name: "reason", Type: "string"}},
+				Returns:     "nothing",
+				Handler:     t.OpenDispute,
+			},
+			{
+				Name:        "ResolveDispute",
+				Description: "Upholds a dispute with a refund of amount, or rejects it, and releases the escrow: what is not refunded returns to the recipient. Emits DisputeResolved",
+				Args: []contract.Arg{
+					{Name: "transferId", Type: "string"},
+					{Name: "decision", Type: "string", Enum: []string{"uphold", "reject"}},
+					{Name: "amount", Type: "integer", Optional: true, Description: "Amount to refund, required to uphold"},
+				},
+				Returns: "nothing",
+				Rule:    &managerRule,
+				Handler: t.ResolveDispute,
+			},
+			{
+				Name:        "SetDisputeWindow",
+				Description: "Sets how long after a transfer it can be disputed",
+				Args:        []contract.Arg{{Name: "window", Type: "integer", Description: "Seconds"}},
+				Returns:     "nothing",
+				Rule:        &managerRule,
+				Handler:     t.SetDisputeWindow,
+			},
+			{
+				Name:        "RegisterAlias",
+				Description: "Registers a human-readable alias for the caller's account: <enrollment ID>@<org>, where org is the caller's MSP ID without the MSP suffix, or, for members of " + access.StaffMSP + ", emp<enrollment ID>. System names such as treasury are reserved for administrators",
+				Args:        []contract.Arg{{Name: "alias", Type: "string", Description: "3-32 lowercase letters, digits, '.' or '-', optionally followed by @org"}},
+				Returns:     "nothing",
+				Handler:     t.RegisterAlias,
+			},
+			{
+				Name:        "ReleaseAlias",
+				Description: "Removes the caller's alias",
+				Returns:     "nothing",
+				Handler:     withoutArgs(t.ReleaseAlias),
+			},
+			{
+				Name:        "ResolveAlias",
+				Description: "Returns the account an alias is registered to",
+				Args:        []contract.Arg{{Name: "alias", Type: "string"}},
+				Returns:     "account ID",
+				ReadOnly:    true,
+				Handler:     t.ResolveAlias,
+			},
+			{
+				Name:        "Reconcile",
+				Description: "Compares the supply computed from balances and escrow with the recorded total",
+				Returns:     "JSON ReconciliationReport",
+				ReadOnly:    true,
+				Handler:     withoutArgs(t.Reconcile),
+			},
+			{
+				Name:        "ReconcileCheckpoint",
+				Description: "Runs the Reconcile report, stores it as a checkpoint and emits ReconciliationCheckpoint",
+				Returns:     "JSON ReconciliationCheckpoint",
+				Handler:     withoutArgs(t.ReconcileCheckpoint),
+			},
+			{
+				Name:        "GetReconciliation",
+				Description: "Returns a stored reconciliation checkpoint",
+				Args:        []contract.Arg{{Name: "checkpointId", Type: "string", Description: "Checkpoint ID or \"latest\""}},
+				Returns:     "JSON ReconciliationCheckpoint",
+				ReadOnly:    true,
+				Handler:     t.GetReconciliation,
+			},
+			{
+				Name:        "RegisterToken",
+				Description: "Records which chaincode on this channel holds a token",
+				Args:        []contract.Arg{{Name: "symbol", Type: "string"}, {Name: "chaincode", Type: "string"}},
+				Returns:     "nothing",
+				Rule:        &managerRule,
+				Handler:     t.RegisterToken,
+			},
+			{
+				Name:        "SetRate",
+				Description: "Sets the conversion rate from this token to another registered token",
+				Args: []contract.Arg{
+					{Name: "from", Type: "string", Description: "This token's symbol"},
+					{Name: "to", Type: "string"},
+					{Name: "numerator", Type: "integer"},
+					{Name: "denominator", Type: "integer"},
+					{Name: "validFrom", Type: "integer", Format: "unix-time"},
+					{Name: "validUntil", Type: "integer", Format: "unix-time", Description: "0 for no end"},
+				},
+				Returns: "nothing",
+				Rule:    &managerRule,
+				Handler: t.SetRate,
+			},
+			{
+				Name:        "Convert",
+				Description: "Burns amount of this token and mints the converted amount of another. Emits Conversion",
+				Args: []contract.Arg{
+					{Name: "from", Type: "string"},
+					{Name: "to", Type: "string"},
+					amountArg,
+					{Name: "minOut", Type: "integer", Description: "Fail if fewer tokens would be received"},
+				},
+				Returns: "JSON Conversion",
+				Handler: t.Convert,
+			},
+			{
+				Name:        "ConvertIn",
+				Description: "Mints converted tokens. Only accepted from Convert in the source token's chaincode",
+				Args: []contract.Arg{
+					{Name: "from", Type: "string"},
+					{Name: "amountIn", Type: "integer"},
+					{Name: "amountOut", Type: "integer"},
+					{Name: "conversionId", Type: "string"},
+				},
+				Returns: "nothing",
+				Handler: t.ConvertIn,
+			},
+			{
+				Name:        "RegisterGovernor",
+				Description: "Trusts a chaincode on this channel, such as multisign, to execute the transfers its members approve",
+				Args:        []contract.Arg{{Name: "chaincode", Type: "string"}},
+				Returns:     "nothing",
+				Rule:        &managerRule,
+				Handler:     t.RegisterGovernor,
+			},
+			{
+				Name:        "ApproveGovernor",
+				Description: "Allows a registered governor chaincode to transfer up to amount from the caller's account, for requests the signer group approved with at least minApproval yes weight",
+				Args: []contract.Arg{
+					{Name: "chaincode", Type: "string"},
+					amountArg,
+					{Name: "signerGroup", Type: "string", Description: "Signer group of the governor that must decide the requests"},
+					{Name: "minApproval", Type: "integer", Description: "Least yes weight a request needs, at least 1"},
+				},
+				Returns: "nothing",
+				Handler: t.ApproveGovernor,
+			},
+			{
+				Name:        "GovernedTransfer",
+				Description: "Executes a transfer a governor approved on the owner's terms, once per request. Only accepted from a registered governor chaincode. Emits Transfer",
+				Args:        []contract.Arg{accountArg("from", "Owner"), accountArg("to", "Recipient"), amountArg, {Name: "requestId", Type: "string"}},
+				Returns:     "transfer ID",
+				Handler:     t.GovernedTransfer,
+			},
+			{
+				Name:        "ExportBalances",
+				Description: "Exports every balance and allowance",
+				Args:        []contract.Arg{pageSizeArg, bookmarkArg},
+				Returns:     "JSON Lines page",
+				ReadOnly:    true,
+				Rule:        &access.Audit,
+				Handler:     t.ExportBalances,
+			},
+			{
+				Name:        "ExportTransfers",
+				Description: "Exports the transfer records with a timestamp in [from, to)",
+				Args: []contract.Arg{
+					{Name: "from", Type: "integer", Format: "unix-time"},
+					{Name: "to", Type: "integer", Format: "unix-time", Description: "0 for no upper bound"},
+					pageSizeArg,
+					bookmarkArg,
+				},
+				Returns:  "JSON Lines page",
+				ReadOnly: true,
+				Rule:     &access.Audit,
+				Handler:  t.ExportTransfers,
+			},
+		}, migrate.Functions(collections)...),
+	}
+}
@@ -0,0 +1,374 @@
+package token
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/chaincode/lib/access"
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Composite key object types for the token registry, rates and conversion records
+const (
+	tokenRegistryPrefix = "tokenregistry"
+	ratePrefix          = "rate"
+	conversionPrefix    = "conversion"
+)
+
+// Rate converts amounts of one token into another as amount * Numerator / Denominator.
+// It is only valid between ValidFrom and ValidUntil (Unix seconds, 0 for no end)
+type Rate struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	Numerator     uint64 `json:"numerator"`
+	Denominator   uint64 `json:"denominator"`
+	ValidFrom     int64  `json:"validFrom"`
+	ValidUntil    int64  `json:"validUntil"`
+	SetBy         string `json:"setBy"`
+	TxID          string `json:"txId"`
+}
+
+// Conversion is the audit record of a single conversion, stored by both chaincodes
+type Conversion struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	ID            string `json:"id"`
+	Direction     string `json:"direction"`
+	Account       string `json:"account"`
+	From          string `json:"from"`
+	To            string `json:"to"`
+	AmountIn      uint64 `json:"amountIn"`
+	AmountOut     uint64 `json:"amountOut"`
+	Numerator     uint64 `json:"numerator,omitempty"`
+	Denominator   uint64 `json:"denominator,omitempty"`
+	Timestamp     int64  `json:"timestamp"`
+}
+
+// getRegisteredChaincode returns the chaincode that holds the token with the given symbol
+func getRegisteredChaincode(stub shim.ChaincodeStubInterface, symbol string) (string, error) {
+	key, err := stub.CreateCompositeKey(tokenRegistryPrefix, []string{symbol})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create key: %s", err)
+	}
+	name, err := stub.GetState(key)
+	if err != nil {
+		return "", fmt.Errorf("Failed to get state: %s", err)
+	}
+	if name == nil {
+		return "", errcode.Newf(errcode.NotFoundCode, "Token is not registered: %s", symbol)
+	}
+	return string(name), nil
+}
+
+func putConversion(stub shim.ChaincodeStubInterface, conversion *Conversion) ([]byte, error) {
+	conversionJSON, err := json.Marshal(conversion)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal conversion: %s", err)
+	}
+	key, err := stub.CreateCompositeKey(conversionPrefix, []string{conversion.ID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create key: %s", err)
+	}
+	err = stub.PutState(key, conversionJSON)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to put state: %s", err)
+	}
+	return conversionJSON, nil
+}
+
+// RegisterToken records which chaincode on this channel holds the token with the given symbol.
+// Only manager-org members can register tokens
+func (t *TokenERC20Chaincode) RegisterToken(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 2: symbol and chaincode name")
+	}
+	if args[0] == "" || args[1] == "" {
+		return errcode.InvalidArgument("Symbol and chaincode name must be non-empty strings")
+	}
+
+	key, err := stub.CreateCompositeKey(tokenRegistryPrefix, []string{args[0]})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to create key: %s", err))
+	}
+	err = stub.PutState(key, []byte(args[1]))
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to put state: %s", err))
+	}
+
+	return shim.Success(nil)
+}
+
+// SetRate sets the conversion rate from this token to another registered token.
+// Only manager-org members can set rates
+func (t *TokenERC20Chaincode) SetRate(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 6 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 6: from symbol, to symbol, numerator, denominator, valid from, valid until")
+	}
+
+	token, err := getToken(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	if args[0] != token.Symbol {
+		return errcode.InvalidArgument(fmt.Sprintf("Rates can only be set from this chaincode's token %s", token.Symbol))
+	}
+	_, err = getRegisteredChaincode(stub, args[1])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	numerator, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil || numerator == 0 {
+		return errcode.InvalidArgument("Numerator must be a positive integer")
+	}
+	denominator, err := strconv.ParseUint(args[3], 10, 64)
+	if err != nil || denominator == 0 {
+		return errcode.InvalidArgument("Denominator must be a positive integer")
+	}
+	validFrom, err := strconv.ParseInt(args[4], 10, 64)
+	if err != nil || validFrom < 0 {
+		return errcode.InvalidArgument("Invalid valid from time")
+	}
+	validUntil, err := strconv.ParseInt(args[5], 10, 64)
+	if err != nil || validUntil < 0 || (validUntil != 0 && validUntil < validFrom) {
+		return errcode.InvalidArgument("Invalid valid until time")
+	}
+
+	setBy, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
+	}
+	rate := Rate{
+		SchemaVersion: rateVersion,
+		From:          args[0],
+		To:            args[1],
+		Numerator:     numerator,
+		Denominator:   denominator,
+		ValidFrom:     validFrom,
+		ValidUntil:    validUntil,
+		SetBy:         hex.EncodeToString(setBy),
+		TxID:          stub.GetTxID(),
+	}
+	rateJSON, err := json.Marshal(rate)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to marshal rate: %s", err))
+	}
+	key, err := stub.CreateCompositeKey(ratePrefix, []string{rate.From, rate.To})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to create key: %s", err))
+	}
+	err = stub.PutState(key, rateJSON)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to put state: %s", err))
+	}
+
+	return shim.Success(nil)
+}
+
+// Convert burns amount of this token from the caller and mints the converted amount
+// of the target token to the caller through the target's chaincode on this channel.
+// Fails if the converted amount is below minOut. This function triggers a Conversion event
+func (t *TokenERC20Chaincode) Convert(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 4 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 4: from symbol, to symbol, amount, minimum out")
+	}
+
+	amount, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil || amount == 0 {
+		return errcode.InvalidArgument("Amount must be a positive integer")
+	}
+	minOut, err := strconv.ParseUint(args[3], 10, 64)
+	if err != nil {
+		return errcode.InvalidArgument(fmt.Sprintf("Invalid minimum out: %s", err))
+	}
+
+	token, err := getToken(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	if args[0] != token.Symbol {
+		return errcode.InvalidArgument(fmt.Sprintf("This chaincode can only convert from %s", token.Symbol))
+	}
+	targetChaincode, err := getRegisteredChaincode(stub, args[1])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	// Load the rate and check it is valid now
+	key, err := stub.CreateCompositeKey(ratePrefix, []string{args[0], args[1]})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to create key: %s", err))
+	}
+	rateJSON, err := stub.GetState(key)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get rate: %s", err))
+	}
+	if rateJSON == nil {
+		return errcode.NotFound(fmt.Sprintf("No rate set from %s to %s", args[0], args[1]))
+	}
+	var rate Rate
+	err = json.Unmarshal(rateJSON, &rate)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to unmarshal rate: %s", err))
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	if now < rate.ValidFrom || (rate.ValidUntil != 0 && now > rate.ValidUntil) {
+		return errcode.Conflict("Rate is not valid at this time")
+	}
+
+	// amountOut = amount * numerator / denominator, rounded down
+	out := new(big.Int).SetUint64(amount)
+	out.Mul(out, new(big.Int).SetUint64(rate.Numerator))
+	out.Div(out, new(big.Int).SetUint64(rate.Denominator))
+	if !out.IsUint64() || out.Uint64() == 0 {
+		return errcode.InvalidArgument("Converted amount is out of range")
+	}
+	amountOut := out.Uint64()
+	if amountOut < minOut {
+		return errcode.Conflict(fmt.Sprintf("Converted amount %d is below minimum %d", amountOut, minOut))
+	}
+
+	// Burn from the caller
+	caller, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
+	}
+	callerHex := hex.EncodeToString(caller)
+	if token.Balance[callerHex] < amount {
+		return errcode.InsufficientFunds("Insufficient balance")
+	}
+	token.Balance[callerHex] -= amount
+	token.Total -= amount
+	err = putToken(stub, token)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	// Mint in the target chaincode; the caller identity is preserved across the call
+	conversionID := stub.GetTxID()
+	response := stub.InvokeChaincode(targetChaincode, [][]byte{
+		[]byte("ConvertIn"),
+		[]byte(args[0]),
+		[]byte(strconv.FormatUint(amount, 10)),
+		[]byte(strconv.FormatUint(amountOut, 10)),
+		[]byte(conversionID),
+	}, "")
+	if response.Status >= shim.ERRORTHRESHOLD {
+		// Keep the code ConvertIn failed with so callers see e.g. CONFLICT
+		failure := errcode.Parse(response)
+		failure.Message = fmt.Sprintf("Failed to mint %s in %s: %s", args[1], targetChaincode, failure.Message)
+		return failure.Response()
+	}
+
+	conversion := Conversion{
+		SchemaVersion: conversionVersion,
+		ID:            conversionID,
+		Direction:     "out",
+		Account:       callerHex,
+		From:          args[0],
+		To:            args[1],
+		AmountIn:      amount,
+		AmountOut:     amountOut,
+		Numerator:     rate.Numerator,
+		Denominator:   rate.Denominator,
+		Timestamp:     now,
+	}
+	conversionJSON, err := putConversion(stub, &conversion)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	err = stub.SetEvent("Conversion", conversionJSON)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to set event: %s", err))
+	}
+
+	return shim.Success(conversionJSON)
+}
+
+// ConvertIn mints the converted amount to the caller. It only accepts calls made by
+// Convert in the chaincode registered for the source token
+func (t *TokenERC20Chaincode) ConvertIn(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 4 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 4: from symbol, amount in, amount out, conversion ID")
+	}
+
+	sourceChaincode, err := getRegisteredChaincode(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	calledChaincode, calledFunction, err := access.TopLevelCall(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	if calledChaincode != sourceChaincode || calledFunction != "Convert" {
+		return errcode.Forbidden(fmt.Sprintf("ConvertIn can only be called by Convert in %s", sourceChaincode))
+	}
+
+	amountIn, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return errcode.InvalidArgument(fmt.Sprintf("Invalid amount in: %s", err))
+	}
+	amountOut, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil {
+		return errcode.InvalidArgument(fmt.Sprintf("Invalid amount out: %s", err))
+	}
+
+	// A conversion can only be minted once
+	key, err := stub.CreateCompositeKey(conversionPrefix, []string{args[3]})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to create key: %s", err))
+	}
+	existing, err := stub.GetState(key)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get state: %s", err))
+	}
+	if existing != nil {
+		return errcode.Conflict(fmt.Sprintf("Conversion has already been processed: %s", args[3]))
+	}
+
+	token, err := getToken(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	caller, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
+	}
+	callerHex := hex.EncodeToString(caller)
+	token.Total += amountOut
+	token.Balance[callerHex] += amountOut
+	err = putToken(stub, token)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	now, err := txTime(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	conversion := Conversion{
+		SchemaVersion: conversionVersion,
+		ID:            args[3],
+		Direction:     "in",
+		Account:       callerHex,
+		From:          args[0],
+		To:            token.Symbol,
+		AmountIn:      amountIn,
+		AmountOut:     amountOut,
+		Timestamp:     now,
+	}
+	_, err = putConversion(stub, &conversion)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	return shim.Success(nil)
+}
@@ -0,0 +1,117 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/chaincode/lib/export"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// BalanceLine is one exported Balance entry. Allowances are exported with the
+// owner and spender split out so auditors can tell them apart from accounts
+type BalanceLine struct {
+	Type    string `json:"type"`
+	Account string `json:"account,omitempty"`
+	Owner   string `json:"owner,omitempty"`
+	Spender string `json:"spender,omitempty"`
+	Amount  uint64 `json:"amount"`
+}
+
+// ExportBalances returns every balance and allowance as JSON Lines, in account order
+func (t *TokenERC20Chaincode) ExportBalances(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) > 2 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 0 to 2: pageSize, bookmark")
+	}
+	page, err := export.PageArgs(args)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	token, err := getToken(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	keys := make([]string, 0, len(token.Balance))
+	for key := range token.Balance {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if page.Skip(key) {
+			continue
+		}
+		line := BalanceLine{Type: "balance", Account: key, Amount: token.Balance[key]}
+		if isAllowanceKey(key) {
+			parts := strings.SplitN(key, "_", 2)
+			line = BalanceLine{Type: "allowance", Owner: parts[0], Spender: parts[1], Amount: token.Balance[key]}
+		}
+		added, err := page.Add(key, line)
+		if err != nil {
+			return errcode.FromError(err)
+		}
+		if !added {
+			break
+		}
+	}
+
+	pageBytes, err := page.Bytes(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	return shim.Success(pageBytes)
+}
+
+// ExportTransfers returns the transfer records with a timestamp in [from, to),
+// given in Unix seconds, as JSON Lines. A to of 0 means no upper bound
+func (t *TokenERC20Chaincode) ExportTransfers(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) < 2 || len(args) > 4 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 2 to 4: from, to, pageSize, bookmark")
+	}
+	from, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return errcode.InvalidArgument("Invalid from time. Expecting Unix seconds")
+	}
+	to, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return errcode.InvalidArgument("Invalid to time. Expecting Unix seconds")
+	}
+	if to != 0 && to <= from {
+		return errcode.InvalidArgument("To time must be after from time")
+	}
+	page, err := export.PageArgs(args[2:])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(transferPrefix, []string{})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to query transfers: %s", err))
+	}
+	err = page.Iterate(resultsIterator, func(key string, value []byte) (interface{}, error) {
+		var record TransferRecord
+		err := json.Unmarshal(value, &record)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal transfer record: %s", err)
+		}
+		if record.Timestamp < from || (to != 0 && record.Timestamp >= to) {
+			return nil, nil
+		}
+		return &record, nil
+	})
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	pageBytes, err := page.Bytes(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	return shim.Success(pageBytes)
+}
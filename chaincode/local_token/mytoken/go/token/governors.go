@@ -0,0 +1,263 @@
+package token
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/chaincode/lib/access"
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Composite key object types for governor chaincodes, the terms owners approved
+// them on and the transfers they executed
+const (
+	governorPrefix         = "governor"
+	governorTermsPrefix    = "governorterms"
+	governedTransferPrefix = "governedtransfer"
+)
+
+// GovernorTerms are the approvals an owner requires before a governor may spend
+// their allowance: a request decided by SignerGroup with a yes weight of at
+// least MinApproval. The requester cannot pick other signers or a lower threshold
+type GovernorTerms struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	SignerGroup   string `json:"signerGroup"`
+	MinApproval   int    `json:"minApproval"`
+}
+
+func governorTermsKey(stub shim.ChaincodeStubInterface, owner string, chaincode string) (string, error) {
+	key, err := stub.CreateCompositeKey(governorTermsPrefix, []string{owner, chaincode})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create key: %s", err)
+	}
+	return key, nil
+}
+
+// getGovernorTerms returns the terms owner approved the governor on, or nil if
+// they never did
+func getGovernorTerms(stub shim.ChaincodeStubInterface, owner string, chaincode string) (*GovernorTerms, error) {
+	key, err := governorTermsKey(stub, owner, chaincode)
+	if err != nil {
+		return nil, err
+	}
+	termsJSON, err := stub.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get state: %s", err)
+	}
+	if termsJSON == nil {
+		return nil, nil
+	}
+	var terms GovernorTerms
+	err = json.Unmarshal(termsJSON, &terms)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal governor terms: %s", err)
+	}
+	return &terms, nil
+}
+
+// admits reports whether a request approved as approval meets the terms
+func (terms *GovernorTerms) admits(approval *access.Approval) bool {
+	return terms != nil && approval.SignerGroup == terms.SignerGroup && approval.YesWeight >= terms.MinApproval
+}
+
+// governorSpender is the spender an owner names in the allowance of a governor
+// chaincode. The colon keeps it apart from hex addresses and aliases
+func governorSpender(chaincode string) string {
+	return "governor:" + chaincode
+}
+
+func isGovernor(stub shim.ChaincodeStubInterface, chaincode string) (bool, error) {
+	key, err := stub.CreateCompositeKey(governorPrefix, []string{chaincode})
+	if err != nil {
+		return false, fmt.Errorf("Failed to create key: %s", err)
+	}
+	value, err := stub.GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("Failed to get state: %s", err)
+	}
+	return value != nil, nil
+}
+
+// RegisterGovernor trusts a chaincode on this channel, such as multisign, to
+// execute transfers its members approved. Only manager-org members can register governors
+func (t *TokenERC20Chaincode) RegisterGovernor(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1: chaincode name")
+	}
+	key, err := stub.CreateCompositeKey(governorPrefix, []string{args[0]})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to create key: %s", err))
+	}
+	err = stub.PutState(key, []byte(args[0]))
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to put state: %s", err))
+	}
+	return shim.Success(nil)
+}
+
+// ApproveGovernor allows a registered governor chaincode to transfer up to amount
+// from the caller's account, e.g. from a treasury account to approved payees,
+// for requests the signer group approved with a yes weight of at least
+// minApproval. Approving again replaces the amount and the terms
+func (t *TokenERC20Chaincode) ApproveGovernor(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 4 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 4: chaincode name, amount, signer group, minimum approval")
+	}
+	governor, err := isGovernor(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	if !governor {
+		return errcode.NotFound(fmt.Sprintf("Chaincode is not a registered governor: %s", args[0]))
+	}
+	amount, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return errcode.InvalidArgument(fmt.Sprintf("Invalid amount: %s", err))
+	}
+	if args[2] == "" {
+		return errcode.InvalidArgument("Signer group must not be empty")
+	}
+	minApproval, err := strconv.Atoi(args[3])
+	if err != nil || minApproval < 1 {
+		return errcode.InvalidArgument("Minimum approval must be a positive integer")
+	}
+
+	token, err := getToken(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	creator, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
+	}
+	owner := hex.EncodeToString(creator)
+	token.Balance[owner+"_"+governorSpender(args[0])] = amount
+	err = putToken(stub, token)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	terms := GovernorTerms{SchemaVersion: governorTermsVersion, SignerGroup: args[2], MinApproval: minApproval}
+	termsJSON, err := json.Marshal(terms)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to marshal governor terms: %s", err))
+	}
+	key, err := governorTermsKey(stub, owner, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	err = stub.PutState(key, termsJSON)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to put state: %s", err))
+	}
+
+	return shim.Success(nil)
+}
+
+// GovernedTransfer moves tokens on behalf of a governor chaincode, using the
+// allowance the owner gave it with ApproveGovernor. It only accepts calls a
+// registered governor makes through access.GovernedFunction for a request
+// approved on the owner's terms, and executes each request at most once
+// Returns the transfer ID. This function triggers a Transfer event
+func (t *TokenERC20Chaincode) GovernedTransfer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 4 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 4: from address, to address, amount, request ID")
+	}
+
+	// The client invoked the governor, which called this chaincode with the request's approval
+	calledChaincode, approval, err := access.GovernedApproval(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	governor, err := isGovernor(stub, calledChaincode)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	if !governor {
+		return errcode.Forbidden("GovernedTransfer can only be called by a registered governor chaincode")
+	}
+
+	requestID := args[3]
+	if approval.RequestID != requestID {
+		return errcode.Forbidden("The governor's approval is for another request")
+	}
+	executedKey, err := stub.CreateCompositeKey(governedTransferPrefix, []string{calledChaincode, requestID})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to create key: %s", err))
+	}
+	executed, err := stub.GetState(executedKey)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get state: %s", err))
+	}
+	if executed != nil {
+		return errcode.New(errcode.ConflictCode, "Request has already been executed").With("transferId", string(executed)).Response()
+	}
+
+	sender, err := resolveAccount(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	receiver, err := resolveAccount(stub, args[1])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	amount, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil {
+		return errcode.InvalidArgument(fmt.Sprintf("Invalid amount: %s", err))
+	}
+
+	terms, err := getGovernorTerms(stub, sender, calledChaincode)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	if !terms.admits(approval) {
+		err := errcode.Newf(errcode.ForbiddenCode, "The owner only allows %s transfers approved on their terms, but signer group %s approved this one with a yes weight of %d", calledChaincode, approval.SignerGroup, approval.YesWeight)
+		if terms != nil {
+			err = err.With("signerGroup", terms.SignerGroup).With("minApproval", terms.MinApproval)
+		}
+		return err.Response()
+	}
+
+	token, err := getToken(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	allowanceKey := sender + "_" + governorSpender(calledChaincode)
+	allowance, exists := token.Balance[allowanceKey]
+	if !exists {
+		return errcode.InsufficientFunds(fmt.Sprintf("No allowance found for governor %s", calledChaincode))
+	}
+	if allowance < amount {
+		return errcode.New(errcode.InsufficientFundsCode, "Insufficient allowance").With("allowance", allowance).With("amount", amount).Response()
+	}
+	if token.Balance[sender] < amount {
+		return errcode.New(errcode.InsufficientFundsCode, "Insufficient balance").With("balance", token.Balance[sender]).With("amount", amount).Response()
+	}
+	token.Balance[allowanceKey] -= amount
+	token.Balance[sender] -= amount
+	token.Balance[receiver] += amount
+	err = putToken(stub, token)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	memo := fmt.Sprintf("%s request %s", calledChaincode, requestID)
+	record, err := recordTransfer(stub, stub.GetTxID(), sender, receiver, amount, memo, nil)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	err = stub.PutState(executedKey, []byte(record.ID))
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to put state: %s", err))
+	}
+	err = setRecordEvent(stub, "Transfer", record)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	return shim.Success([]byte(record.ID))
+}
@@ -0,0 +1,115 @@
+package token
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// idempotencyPrefix is the composite key object type for stored outcomes
+const idempotencyPrefix = "idempotency"
+
+// idempotencyTransientKey is the transient map field clients put the key in, e.g.
+// contract.createTransaction('transfer').setTransient({idempotencyKey: Buffer.from(id)})
+const idempotencyTransientKey = "idempotencyKey"
+
+// maxIdempotencyKeyLength bounds the client-supplied key, in bytes
+const maxIdempotencyKeyLength = 128
+
+// IdempotentOutcome is the stored result of the first call made with a key
+type IdempotentOutcome struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Function      string `json:"function"`
+	ParamsHash    string `json:"paramsHash"`
+	TxID          string `json:"txId"`
+	Timestamp     int64  `json:"timestamp"`
+	Payload       []byte `json:"payload"`
+}
+
+// paramsHash fingerprints a call so replays with different parameters can be detected
+func paramsHash(function string, args []string) string {
+	sum := sha256.Sum256([]byte(function + "\x00" + strings.Join(args, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// withIdempotency runs handler at most once per caller and client-supplied key.
+// Without a key the handler always runs. A replay with the same key and parameters
+// returns the original result without running the handler again; a replay with
+// different parameters is rejected. Only successful outcomes are stored, since a
+// failed transaction is never committed. Concurrent first attempts with the same
+// key read the same outcome key, so all but one fail MVCC validation
+func withIdempotency(stub shim.ChaincodeStubInterface, function string, args []string, handler func(shim.ChaincodeStubInterface, []string) pb.Response) pb.Response {
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get transient data: %s", err))
+	}
+	idempotencyKey := string(transient[idempotencyTransientKey])
+	if idempotencyKey == "" {
+		return handler(stub, args)
+	}
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		return errcode.InvalidArgument(fmt.Sprintf("Idempotency key must be at most %d bytes", maxIdempotencyKeyLength))
+	}
+
+	// Keys are scoped to the caller so clients cannot collide with each other
+	creator, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
+	}
+	creatorHash := sha256.Sum256(creator)
+	key, err := stub.CreateCompositeKey(idempotencyPrefix, []string{hex.EncodeToString(creatorHash[:]), idempotencyKey})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to create key: %s", err))
+	}
+
+	hash := paramsHash(function, args)
+	outcomeJSON, err := stub.GetState(key)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get state: %s", err))
+	}
+	if outcomeJSON != nil {
+		var outcome IdempotentOutcome
+		err = json.Unmarshal(outcomeJSON, &outcome)
+		if err != nil {
+			return errcode.Internal(fmt.Sprintf("Failed to unmarshal outcome: %s", err))
+		}
+		if outcome.ParamsHash != hash {
+			return errcode.Conflict(fmt.Sprintf("Idempotency key was already used for a different %s call in transaction %s", outcome.Function, outcome.TxID))
+		}
+		return shim.Success(outcome.Payload)
+	}
+
+	response := handler(stub, args)
+	if response.Status >= shim.ERRORTHRESHOLD {
+		return response
+	}
+
+	now, err := txTime(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	outcome := IdempotentOutcome{
+		SchemaVersion: idempotencyVersion,
+		Function:      function,
+		ParamsHash:    hash,
+		TxID:          stub.GetTxID(),
+		Timestamp:     now,
+		Payload:       response.Payload,
+	}
+	outcomeJSON, err = json.Marshal(outcome)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to marshal outcome: %s", err))
+	}
+	err = stub.PutState(key, outcomeJSON)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to put state: %s", err))
+	}
+
+	return response
+}
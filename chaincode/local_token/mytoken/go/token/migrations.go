@@ -0,0 +1,50 @@
+package token
+
+import (
+	"github.com/chaincode/lib/migrate"
+)
+
+// Schema versions of the token state, its ledger records and registries. The
+// token is at version 2 since escrow, transfers since disputes record their hold
+const (
+	tokenVersion          = 2
+	standingOrderVersion  = 1
+	transferVersion       = 2
+	aliasVersion          = 1
+	reconciliationVersion = 1
+	rateVersion           = 1
+	conversionVersion     = 1
+	idempotencyVersion    = 1
+	governorTermsVersion  = 1
+)
+
+// collections registers the upgrade functions for every stored object type.
+// Index entries and plain configuration values are not versioned. Active
+// standing orders stored before the due order index existed are added to it
+var collections = []migrate.Collection{
+	{Name: "token", Key: "token", Upgrades: []migrate.Upgrade{migrate.Stamp, addEscrow}},
+	{Name: "standingorder", ObjectType: standingOrderPrefix, Upgrades: []migrate.Upgrade{migrate.Stamp}, Index: reindexStandingOrder, Indexed: standingOrderIndexed},
+	{Name: "transfer", ObjectType: transferPrefix, Upgrades: []migrate.Upgrade{migrate.Stamp, addDisputeHold}},
+	{Name: "alias", ObjectType: aliasPrefix, Upgrades: []migrate.Upgrade{migrate.Stamp}},
+	{Name: "reconciliation", ObjectType: reconciliationPrefix, Upgrades: []migrate.Upgrade{migrate.Stamp}},
+	{Name: "rate", ObjectType: ratePrefix, Upgrades: []migrate.Upgrade{migrate.Stamp}},
+	{Name: "conversion", ObjectType: conversionPrefix, Upgrades: []migrate.Upgrade{migrate.Stamp}},
+	{Name: "idempotency", ObjectType: idempotencyPrefix, Upgrades: []migrate.Upgrade{migrate.Stamp}},
+	{Name: "governorterms", ObjectType: governorTermsPrefix, Upgrades: []migrate.Upgrade{migrate.Stamp}},
+}
+
+// addEscrow gives the version 1 token an empty escrow. Disputes held nothing
+// before escrow existed
+func addEscrow(obj map[string]interface{}) error {
+	obj["escrow"] = 0
+	return nil
+}
+
+// addDisputeHold records that the dispute of a version 1 transfer, if any,
+// holds nothing in escrow
+func addDisputeHold(obj map[string]interface{}) error {
+	if dispute, ok := obj["dispute"].(map[string]interface{}); ok {
+		dispute["held"] = 0
+	}
+	return nil
+}
@@ -1,4 +1,4 @@
-package main
+package token
 
 import (
 	"encoding/hex"
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/chaincode/lib/errcode"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	pb "github.com/hyperledger/fabric/protos/peer"
 )
@@ -16,11 +17,55 @@ type TokenERC20Chaincode struct {
 
 // Token represents an ERC20 token
 type Token struct {
-	Name     string            `json:"name"`
-	Symbol   string            `json:"symbol"`
-	Total    uint64            `json:"total"`
-	Decimals uint8             `json:"decimals"`
-	Balance  map[string]uint64 `json:"balance"`
+	SchemaVersion int               `json:"schemaVersion"`
+	Name          string            `json:"name"`
+	Symbol        string            `json:"symbol"`
+	Total         uint64            `json:"total"`
+	Decimals      uint8             `json:"decimals"`
+	Balance       map[string]uint64 `json:"balance"`
+	Escrow        uint64            `json:"escrow"`
+}
+
+// getToken loads the token state from the ledger
+func getToken(stub shim.ChaincodeStubInterface) (*Token, error) {
+	tokenJSON, err := stub.GetState("token")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get token: %s", err)
+	}
+	if tokenJSON == nil {
+		return nil, errcode.New(errcode.NotFoundCode, "Token state does not exist")
+	}
+	var token Token
+	err = json.Unmarshal(tokenJSON, &token)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal token: %s", err)
+	}
+	if token.Balance == nil {
+		token.Balance = make(map[string]uint64)
+	}
+	return &token, nil
+}
+
+// putToken saves the token state to the ledger
+func putToken(stub shim.ChaincodeStubInterface, token *Token) error {
+	tokenJSON, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal token: %s", err)
+	}
+	err = stub.PutState("token", tokenJSON)
+	if err != nil {
+		return fmt.Errorf("Failed to put state: %s", err)
+	}
+	return nil
+}
+
+// txTime returns the transaction timestamp in Unix seconds
+func txTime(stub shim.ChaincodeStubInterface) (int64, error) {
+	ts, err := stub.GetTxTimestamp()
+	if err != nil {
+		return 0, fmt.Errorf("Failed to get transaction timestamp: %s", err)
+	}
+	return ts.GetSeconds(), nil
 }
 
 func (t *TokenERC20Chaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
@@ -31,7 +76,7 @@ func (t *TokenERC20Chaincode) Init(stub shim.ChaincodeStubInterface) pb.Response
 func (t *TokenERC20Chaincode) Initialize(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	// Check the number of arguments
 	if len(args) != 4 {
-		return shim.Error("Incorrect number of arguments. Expected 4: name, symbol, total supply, decimals")
+		return errcode.InvalidArgument("Incorrect number of arguments. Expected 4: name, symbol, total supply, decimals")
 	}
 
 	// Retrieve information from the arguments
@@ -39,26 +84,27 @@ func (t *TokenERC20Chaincode) Initialize(stub shim.ChaincodeStubInterface, args
 	symbol := args[1]
 	totalSupply, err := strconv.ParseUint(args[2], 10, 64)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Invalid total supply: %s", err))
+		return errcode.InvalidArgument(fmt.Sprintf("Invalid total supply: %s", err))
 	}
 	decimals, err := strconv.ParseUint(args[3], 10, 8)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Invalid decimals: %s", err))
+		return errcode.InvalidArgument(fmt.Sprintf("Invalid decimals: %s", err))
 	}
 
 	// Initialize the token
 	token := Token{
-		Name:     name,
-		Symbol:   symbol,
-		Total:    totalSupply,
-		Decimals: uint8(decimals),
-		Balance:  make(map[string]uint64),
+		SchemaVersion: tokenVersion,
+		Name:          name,
+		Symbol:        symbol,
+		Total:         totalSupply,
+		Decimals:      uint8(decimals),
+		Balance:       make(map[string]uint64),
 	}
 
 	// Get information of the transaction creator
 	creator, err := stub.GetCreator()
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get transaction creator information: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get transaction creator information: %s", err))
 	}
 
 	// Set total supply to the balance of the transaction creator
@@ -67,46 +113,19 @@ func (t *TokenERC20Chaincode) Initialize(stub shim.ChaincodeStubInterface, args
 	// Save the token state to the ledger
 	tokenJSON, err := json.Marshal(token)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to encode token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to encode token: %s", err))
 	}
 	err = stub.PutState("token", tokenJSON)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to save state: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to save state: %s", err))
 	}
 
 	return shim.Success(nil)
 }
 
+// Invoke dispatches to the function catalogue in contract.go
 func (t *TokenERC20Chaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
-	function, args := stub.GetFunctionAndParameters()
-
-	switch function {
-	case "Initialize":
-		return t.Initialize(stub, args)
-	case "Mint":
-		return t.Mint(stub, args)
-	case "ClientAccountBalance":
-		return t.ClientAccountBalance(stub)
-	case "ClientAccountID":
-		return t.ClientAccountID(stub)
-	case "transfer":
-		return t.Transfer(stub, args)
-	case "Approve":
-		return t.Approve(stub, args)
-	case "Allowance":
-		return t.Allowance(stub, args)
-	case "transferFrom":
-		return t.TransferFrom(stub, args)
-	case "balanceOf":
-		return t.BalanceOf(stub, args)
-	case "name":
-		return t.Name(stub)
-	case "symbol":
-		return t.Symbol(stub)
-	case "totalSupply":
-		return t.TotalSupply(stub)
-	}
-	return shim.Error("Invalid function name")
+	return t.catalogue().Invoke(stub)
 }
 
 // Mint creates new tokens and adds them to the minter's account balance
@@ -114,30 +133,30 @@ func (t *TokenERC20Chaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Respon
 func (t *TokenERC20Chaincode) Mint(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	// Check number of arguments
 	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1: amount")
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1: amount")
 	}
 
 	// Parse amount
 	amount, err := strconv.ParseUint(args[0], 10, 64)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Invalid amount: %s", err))
+		return errcode.InvalidArgument(fmt.Sprintf("Invalid amount: %s", err))
 	}
 
 	// Load token state
 	tokenJSON, err := stub.GetState("token")
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get token: %s", err))
 	}
 	var token Token
 	err = json.Unmarshal(tokenJSON, &token)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to unmarshal token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to unmarshal token: %s", err))
 	}
 
 	// Add amount to total supply and minter's balance
 	creator, err := stub.GetCreator()
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get creator: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
 	}
 	creatorHex := hex.EncodeToString(creator)
 	token.Total += amount
@@ -146,17 +165,17 @@ func (t *TokenERC20Chaincode) Mint(stub shim.ChaincodeStubInterface, args []stri
 	// Update token state
 	tokenJSON, err = json.Marshal(token)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to marshal token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to marshal token: %s", err))
 	}
 	err = stub.PutState("token", tokenJSON)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to put state: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to put state: %s", err))
 	}
 
 	// Trigger Transfer event
 	err = stub.SetEvent("Transfer", []byte(fmt.Sprintf("Minted %d tokens to %s", amount, creatorHex)))
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to set event: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to set event: %s", err))
 	}
 
 	return shim.Success(nil)
@@ -167,18 +186,18 @@ func (t *TokenERC20Chaincode) ClientAccountBalance(stub shim.ChaincodeStubInterf
 	// Get client ID
 	clientID, err := stub.GetCreator()
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get client ID: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get client ID: %s", err))
 	}
 
 	// Load token state
 	tokenJSON, err := stub.GetState("token")
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get token: %s", err))
 	}
 	var token Token
 	err = json.Unmarshal(tokenJSON, &token)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to unmarshal token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to unmarshal token: %s", err))
 	}
 	// Get balance of client ID
 	clientIDHex := hex.EncodeToString(clientID)
@@ -189,11 +208,11 @@ func (t *TokenERC20Chaincode) ClientAccountBalance(stub shim.ChaincodeStubInterf
 		token.Balance[clientIDHex] = balance
 		tokenJSON, err := json.Marshal(token)
 		if err != nil {
-			return shim.Error(fmt.Sprintf("Failed to marshal token: %s", err))
+			return errcode.Internal(fmt.Sprintf("Failed to marshal token: %s", err))
 		}
 		err = stub.PutState("token", tokenJSON)
 		if err != nil {
-			return shim.Error(fmt.Sprintf("Failed to update token state: %s", err))
+			return errcode.Internal(fmt.Sprintf("Failed to update token state: %s", err))
 		}
 	}
 
@@ -205,7 +224,7 @@ func (t *TokenERC20Chaincode) ClientAccountID(stub shim.ChaincodeStubInterface)
 	// Get client ID
 	clientID, err := stub.GetCreator()
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get client ID: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get client ID: %s", err))
 	}
 
 	// encodedClientID := base64.StdEncoding.EncodeToString(clientID)
@@ -214,57 +233,76 @@ func (t *TokenERC20Chaincode) ClientAccountID(stub shim.ChaincodeStubInterface)
 }
 
 // Transfer transfers tokens from client account to recipient account
-// recipient account must be a valid clientID as returned by the ClientID() function
-// This function triggers a Transfer event
+// recipient account must be a valid clientID as returned by the ClientID() function,
+// or an alias registered with RegisterAlias. An optional memo and JSON reference
+// are stored with the transfer record
+// Returns the transfer ID. This function triggers a Transfer event
 func (t *TokenERC20Chaincode) Transfer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	// Check number of arguments
-	if len(args) != 2 {
-		return shim.Error("Incorrect number of arguments. Expecting 2: to address and amount")
+	if len(args) < 2 || len(args) > 4 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 2 to 4: to address, amount, optional memo and reference")
+	}
+	memo, reference, err := parseMemo(args[2:])
+	if err != nil {
+		return errcode.FromError(err)
 	}
 
 	// Parse amount
 	amount, err := strconv.ParseUint(args[1], 10, 64)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Invalid amount: %s", err))
+		return errcode.InvalidArgument(fmt.Sprintf("Invalid amount: %s", err))
 	}
 	// Load token state
 	tokenJSON, err := stub.GetState("token")
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get token: %s", err))
 	}
 	var token Token
 	err = json.Unmarshal(tokenJSON, &token)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to unmarshal token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to unmarshal token: %s", err))
 	}
 
 	// Deduct amount from sender's balance
 	sender, err := stub.GetCreator()
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get creator: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
 	}
 	senderHex := hex.EncodeToString(sender)
 	senderBalance := token.Balance[senderHex]
 	if senderBalance < amount {
-		return shim.Error("Insufficient balance")
+		return errcode.New(errcode.InsufficientFundsCode, "Insufficient balance").With("balance", senderBalance).With("amount", amount).Response()
 	}
 	token.Balance[senderHex] -= amount
 
 	// Add amount to receiver's balance
-	receiver := args[0]
+	receiver, err := resolveAccount(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
 	token.Balance[receiver] += amount
 
 	// Update token state
 	tokenJSON, err = json.Marshal(token)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to marshal token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to marshal token: %s", err))
 	}
 	err = stub.PutState("token", tokenJSON)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to put state: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to put state: %s", err))
 	}
 
-	return shim.Success(nil)
+	// Record the transfer so it can be refunded or disputed
+	record, err := recordTransfer(stub, stub.GetTxID(), senderHex, receiver, amount, memo, reference)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	err = setRecordEvent(stub, "Transfer", record)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	return shim.Success([]byte(record.ID))
 }
 
 // Approve allows spender to withdraw from owner's account multiple times, up to the amount
@@ -272,51 +310,54 @@ func (t *TokenERC20Chaincode) Transfer(stub shim.ChaincodeStubInterface, args []
 func (t *TokenERC20Chaincode) Approve(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	// Check number of arguments
 	if len(args) != 2 {
-		return shim.Error("Incorrect number of arguments. Expecting 2: spender address and amount")
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 2: spender address and amount")
 	}
 
 	// Parse amount
 	amount, err := strconv.ParseUint(args[1], 10, 64)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Invalid amount: %s", err))
+		return errcode.InvalidArgument(fmt.Sprintf("Invalid amount: %s", err))
 	}
 
 	// Load token state
 	tokenJSON, err := stub.GetState("token")
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get token: %s", err))
 	}
 	var token Token
 	err = json.Unmarshal(tokenJSON, &token)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to unmarshal token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to unmarshal token: %s", err))
 	}
 
 	// Get miner's address
 	miner, err := stub.GetCreator()
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get creator: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
 	}
 	minerHex := hex.EncodeToString(miner)
 
 	// Set allowance of spender from owner
-	spender := args[0]
+	spender, err := resolveAccount(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
 	token.Balance[minerHex+"_"+spender] = amount
 
 	// Update token state
 	tokenJSON, err = json.Marshal(token)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to marshal token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to marshal token: %s", err))
 	}
 	err = stub.PutState("token", tokenJSON)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to put state: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to put state: %s", err))
 	}
 
 	// Trigger Approval event
 	err = stub.SetEvent("Approval", []byte(fmt.Sprintf("Approved %d tokens to %s", amount, spender)))
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to set event: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to set event: %s", err))
 	}
 
 	return shim.Success(nil)
@@ -326,73 +367,92 @@ func (t *TokenERC20Chaincode) Approve(stub shim.ChaincodeStubInterface, args []s
 func (t *TokenERC20Chaincode) Allowance(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	// Check number of arguments
 	if len(args) != 2 {
-		return shim.Error("Incorrect number of arguments. Expecting 2: owner address and spender address")
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 2: owner address and spender address")
 	}
 
-	miner := args[0]
-	spender := args[1]
+	miner, err := resolveAccount(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	spender, err := resolveAccount(stub, args[1])
+	if err != nil {
+		return errcode.FromError(err)
+	}
 
 	// Load token state
 	tokenJSON, err := stub.GetState("token")
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get token: %s", err))
 	}
 	var token Token
 	err = json.Unmarshal(tokenJSON, &token)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to unmarshal token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to unmarshal token: %s", err))
 	}
 
 	// Get allowance of spender from owner
 	allowance, exists := token.Balance[miner+"_"+spender]
 	if !exists {
-		return shim.Error("No allowance found")
+		return errcode.NotFound("No allowance found")
 	}
 
 	return shim.Success([]byte(fmt.Sprintf("%d", allowance)))
 }
 
+// TransferFrom transfers tokens from an owner's account using the caller's allowance.
+// An optional memo and JSON reference are stored with the transfer record
+// Returns the transfer ID. This function triggers a Transfer event
 func (t *TokenERC20Chaincode) TransferFrom(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	// Check number of arguments
-	if len(args) != 3 {
-		return shim.Error("Incorrect number of arguments. Expecting 3: from address, to address, and amount")
+	if len(args) < 3 || len(args) > 5 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 3 to 5: from address, to address, amount, optional memo and reference")
+	}
+	memo, reference, err := parseMemo(args[3:])
+	if err != nil {
+		return errcode.FromError(err)
 	}
 
-	// Load token state
-	sender := args[0]
-	receiver := args[1]
+	// Resolve addresses or aliases
+	sender, err := resolveAccount(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	receiver, err := resolveAccount(stub, args[1])
+	if err != nil {
+		return errcode.FromError(err)
+	}
 
 	// Parse amount
 	amount, err := strconv.ParseUint(args[2], 10, 64)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Invalid amount: %s", err))
+		return errcode.InvalidArgument(fmt.Sprintf("Invalid amount: %s", err))
 	}
 
 	// Load token state
 	tokenJSON, err := stub.GetState("token")
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get token: %s", err))
 	}
 	var token Token
 	err = json.Unmarshal(tokenJSON, &token)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to unmarshal token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to unmarshal token: %s", err))
 	}
 
 	// Get spider's address
 	spender, err := stub.GetCreator()
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get creator: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
 	}
 	spenderHex := hex.EncodeToString(spender)
 
 	// Check the allowance of the sender
 	allowance, exists := token.Balance[sender+"_"+spenderHex]
 	if !exists {
-		return shim.Error("No allowance found")
+		return errcode.InsufficientFunds("No allowance found")
 	}
 	if allowance < amount {
-		return shim.Error("Insufficient allowance")
+		return errcode.New(errcode.InsufficientFundsCode, "Insufficient allowance").With("allowance", allowance).With("amount", amount).Response()
 	}
 
 	// Deduct the amount from the sender's allowance
@@ -401,7 +461,7 @@ func (t *TokenERC20Chaincode) TransferFrom(stub shim.ChaincodeStubInterface, arg
 	// Deduct amount from sender's balance
 	senderBalance := token.Balance[sender]
 	if senderBalance < amount {
-		return shim.Error("Insufficient balance")
+		return errcode.New(errcode.InsufficientFundsCode, "Insufficient balance").With("balance", senderBalance).With("amount", amount).Response()
 	}
 	token.Balance[sender] -= amount
 
@@ -411,46 +471,59 @@ func (t *TokenERC20Chaincode) TransferFrom(stub shim.ChaincodeStubInterface, arg
 	// Update token state
 	tokenJSON, err = json.Marshal(token)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to marshal token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to marshal token: %s", err))
 	}
 	err = stub.PutState("token", tokenJSON)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to put state: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to put state: %s", err))
 	}
 
-	return shim.Success(nil)
+	// Record the transfer so it can be refunded or disputed
+	record, err := recordTransfer(stub, stub.GetTxID(), sender, receiver, amount, memo, reference)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	err = setRecordEvent(stub, "Transfer", record)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	return shim.Success([]byte(record.ID))
 }
 
 // BalanceOf returns the balance of the given account
 func (t *TokenERC20Chaincode) BalanceOf(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	// Check number of arguments
 	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1: to address")
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1: to address")
+	}
+	if args[0] == "" {
+		return errcode.InvalidArgument("Address argument must be a non-empty string")
 	}
-	address := args[0]
-	if address == "" {
-		return shim.Error("Address argument must be a non-empty string")
+	address, err := resolveAccount(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
 	}
 
 	// Load token state
 	tokenJSON, err := stub.GetState("token")
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get token: %s", err))
 	}
 	if tokenJSON == nil {
-		return shim.Error("Token state does not exist")
+		return errcode.NotFound("Token state does not exist")
 	}
 
 	var token Token
 	err = json.Unmarshal(tokenJSON, &token)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to unmarshal token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to unmarshal token: %s", err))
 	}
 
 	// Get balance of specified address
 	balance, exists := token.Balance[address]
 	if !exists {
-		return shim.Error(fmt.Sprintf("No balance found for address: %s", address))
+		return errcode.NotFound(fmt.Sprintf("No balance found for address: %s", address))
 	}
 
 	return shim.Success([]byte(fmt.Sprintf("%d", balance)))
@@ -462,12 +535,12 @@ func (t *TokenERC20Chaincode) Name(stub shim.ChaincodeStubInterface) pb.Response
 	// Load token state
 	tokenJSON, err := stub.GetState("token")
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get token: %s", err))
 	}
 	var token Token
 	err = json.Unmarshal(tokenJSON, &token)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to unmarshal token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to unmarshal token: %s", err))
 	}
 
 	return shim.Success([]byte(token.Name))
@@ -479,12 +552,12 @@ func (t *TokenERC20Chaincode) Symbol(stub shim.ChaincodeStubInterface) pb.Respon
 	// Load token state
 	tokenJSON, err := stub.GetState("token")
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get token: %s", err))
 	}
 	var token Token
 	err = json.Unmarshal(tokenJSON, &token)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to unmarshal token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to unmarshal token: %s", err))
 	}
 
 	return shim.Success([]byte(token.Symbol))
@@ -495,20 +568,13 @@ func (t *TokenERC20Chaincode) TotalSupply(stub shim.ChaincodeStubInterface) pb.R
 	// Load token state
 	tokenJSON, err := stub.GetState("token")
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to get token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to get token: %s", err))
 	}
 	var token Token
 	err = json.Unmarshal(tokenJSON, &token)
 	if err != nil {
-		return shim.Error(fmt.Sprintf("Failed to unmarshal token: %s", err))
+		return errcode.Internal(fmt.Sprintf("Failed to unmarshal token: %s", err))
 	}
 
 	return shim.Success([]byte(fmt.Sprintf("%d", token.Total)))
 }
-
-func main() {
-	err := shim.Start(new(TokenERC20Chaincode))
-	if err != nil {
-		fmt.Printf("Error starting TokenERC20Chaincode: %s", err)
-	}
-}
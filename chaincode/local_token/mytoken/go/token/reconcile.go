@@ -0,0 +1,244 @@
+package token
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/chaincode/lib/access"
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// reconciliationPrefix is the composite key object type for reconciliation checkpoints
+const reconciliationPrefix = "reconciliation"
+
+// Discrepancy describes an account entry that does not add up
+type Discrepancy struct {
+	Account string `json:"account"`
+	Balance uint64 `json:"balance"`
+	Issue   string `json:"issue"`
+}
+
+// ReconciliationReport compares the supply computed from balances and escrow
+// with Token.Total
+type ReconciliationReport struct {
+	Recorded      uint64        `json:"recorded"`
+	Computed      uint64        `json:"computed"`
+	Escrow        uint64        `json:"escrow"`
+	Difference    int64         `json:"difference"`
+	Balanced      bool          `json:"balanced"`
+	Accounts      int           `json:"accounts"`
+	Allowances    int           `json:"allowances"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// ReconciliationCheckpoint is a report written to the ledger for auditors.
+// The checkpoint is attributable to the identity that signed the transaction
+type ReconciliationCheckpoint struct {
+	SchemaVersion int                  `json:"schemaVersion"`
+	ID            string               `json:"id"`
+	Timestamp     int64                `json:"timestamp"`
+	Checker       string               `json:"checker"`
+	CheckerMSP    string               `json:"checkerMsp"`
+	Report        ReconciliationReport `json:"report"`
+}
+
+// isAccountID reports whether s is a hex-encoded account
+func isAccountID(s string) bool {
+	_, err := hex.DecodeString(s)
+	return s != "" && err == nil
+}
+
+// isAllowanceKey reports whether a Balance entry is an owner_spender allowance:
+// an account, an underscore and a spender, which is an account or a governor
+// chaincode
+func isAllowanceKey(key string) bool {
+	parts := strings.Split(key, "_")
+	if len(parts) != 2 || !isAccountID(parts[0]) {
+		return false
+	}
+	spender := parts[1]
+	if chaincode := strings.TrimPrefix(spender, governorSpender("")); chaincode != spender {
+		return chaincode != ""
+	}
+	return isAccountID(spender)
+}
+
+// difference returns computed - recorded, and false if it does not fit an int64
+func difference(computed uint64, recorded uint64) (int64, bool) {
+	if computed >= recorded {
+		d := computed - recorded
+		if d > math.MaxInt64 {
+			return math.MaxInt64, false
+		}
+		return int64(d), true
+	}
+	d := recorded - computed
+	if d > math.MaxInt64 {
+		return math.MinInt64, false
+	}
+	return -int64(d), true
+}
+
+// reconcile walks every balance and checks it against the recorded total supply.
+// Funds are held in Token.Balance, and in Token.Escrow while a dispute holds
+// them; there are no vesting pools to include
+func reconcile(token *Token) ReconciliationReport {
+	report := ReconciliationReport{Recorded: token.Total, Escrow: token.Escrow, Computed: token.Escrow, Discrepancies: []Discrepancy{}}
+
+	accounts := make([]string, 0, len(token.Balance))
+	for account := range token.Balance {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+
+	overflow := false
+	for _, account := range accounts {
+		balance := token.Balance[account]
+		if isAllowanceKey(account) {
+			report.Allowances++
+			continue
+		}
+		report.Accounts++
+		// Underscores only belong in allowance keys, but Transfer used to credit
+		// any recipient, so such balances still count toward the supply
+		if strings.Contains(account, "_") {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{account, balance, "balance held under a key with '_' that is not an allowance"})
+		}
+
+		if balance > math.MaxUint64-report.Computed {
+			overflow = true
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{account, balance, "balance overflows total supply"})
+			continue
+		}
+		report.Computed += balance
+
+		if balance == 0 {
+			continue
+		}
+		// Funds held under keys nobody can sign for are unreachable
+		if strings.Contains(account, "_") {
+			continue
+		} else if account == "" {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{account, balance, "balance held by empty address"})
+		} else if isAlias(account) {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{account, balance, "balance held by an alias instead of an account"})
+		} else if _, err := hex.DecodeString(account); err != nil {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{account, balance, "balance held by a non-hex address"})
+		}
+	}
+
+	var fits bool
+	report.Difference, fits = difference(report.Computed, report.Recorded)
+	if !fits {
+		overflow = true
+		report.Discrepancies = append(report.Discrepancies, Discrepancy{"", 0, "difference between computed and recorded supply overflows"})
+	}
+	report.Balanced = !overflow && report.Difference == 0
+	return report
+}
+
+// Reconcile reports the computed versus recorded supply with per-account discrepancies
+func (t *TokenERC20Chaincode) Reconcile(stub shim.ChaincodeStubInterface) pb.Response {
+	token, err := getToken(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	reportJSON, err := json.Marshal(reconcile(token))
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to marshal report: %s", err))
+	}
+	return shim.Success(reportJSON)
+}
+
+// ReconcileCheckpoint runs the Reconcile report and writes it to the ledger, which
+// keepers can do periodically. This function triggers a ReconciliationCheckpoint event
+func (t *TokenERC20Chaincode) ReconcileCheckpoint(stub shim.ChaincodeStubInterface) pb.Response {
+	token, err := getToken(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	report := reconcile(token)
+
+	checker, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
+	}
+	checkerID, err := access.GetIdentity(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	record := ReconciliationCheckpoint{
+		SchemaVersion: reconciliationVersion,
+		ID:            stub.GetTxID(),
+		Timestamp:     now,
+		Checker:       hex.EncodeToString(checker),
+		CheckerMSP:    checkerID.MSPID,
+		Report:        report,
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to marshal checkpoint: %s", err))
+	}
+
+	key, err := stub.CreateCompositeKey(reconciliationPrefix, []string{record.ID})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to create key: %s", err))
+	}
+	err = stub.PutState(key, recordJSON)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to put state: %s", err))
+	}
+	err = stub.PutState("lastReconciliation", []byte(record.ID))
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to put state: %s", err))
+	}
+	err = stub.SetEvent("ReconciliationCheckpoint", recordJSON)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to set event: %s", err))
+	}
+
+	return shim.Success(recordJSON)
+}
+
+// GetReconciliation returns a stored reconciliation checkpoint, or the most recent one for "latest"
+func (t *TokenERC20Chaincode) GetReconciliation(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1: checkpoint ID or \"latest\"")
+	}
+
+	checkpointID := args[0]
+	if checkpointID == "latest" {
+		latest, err := stub.GetState("lastReconciliation")
+		if err != nil {
+			return errcode.Internal(fmt.Sprintf("Failed to get state: %s", err))
+		}
+		if latest == nil {
+			return errcode.NotFound("No reconciliation checkpoint has been written")
+		}
+		checkpointID = string(latest)
+	}
+
+	key, err := stub.CreateCompositeKey(reconciliationPrefix, []string{checkpointID})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to create key: %s", err))
+	}
+	recordJSON, err := stub.GetState(key)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get state: %s", err))
+	}
+	if recordJSON == nil {
+		return errcode.NotFound(fmt.Sprintf("Reconciliation checkpoint does not exist: %s", checkpointID))
+	}
+
+	return shim.Success(recordJSON)
+}
@@ -0,0 +1,123 @@
+package token
+
+import (
+	"math"
+	"testing"
+
+	"github.com/chaincode/lib/access"
+)
+
+func TestIsAllowanceKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"aa01_bb02", true},
+		{"aa01_governor:multisign", true},
+		{"aa01", false},
+		{"aa01_", false},
+		{"_bb02", false},
+		{"aa01_governor:", false},
+		{"aa01_bb02_cc03", false},
+		{"alice_bb02", false},
+		{"aa01_bob", false},
+	}
+	for _, tt := range tests {
+		if got := isAllowanceKey(tt.key); got != tt.want {
+			t.Errorf("isAllowanceKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestDifference(t *testing.T) {
+	tests := []struct {
+		name               string
+		computed, recorded uint64
+		want               int64
+		wantOK             bool
+	}{
+		{name: "equal", computed: 5, recorded: 5, want: 0, wantOK: true},
+		{name: "surplus", computed: 7, recorded: 5, want: 2, wantOK: true},
+		{name: "shortfall", computed: 5, recorded: 7, want: -2, wantOK: true},
+		{name: "largest surplus", computed: math.MaxInt64, recorded: 0, want: math.MaxInt64, wantOK: true},
+		{name: "surplus overflows", computed: math.MaxUint64, recorded: 0, want: math.MaxInt64},
+		{name: "largest shortfall", computed: 0, recorded: math.MaxInt64, want: -math.MaxInt64, wantOK: true},
+		{name: "shortfall overflows", computed: 0, recorded: math.MaxUint64, want: math.MinInt64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := difference(tt.computed, tt.recorded)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("difference() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         Token
+		wantBalanced  bool
+		wantAccounts  int
+		wantAllowance int
+		wantIssues    int
+	}{
+		{
+			name:         "balanced",
+			token:        Token{Total: 30, Balance: map[string]uint64{"0a01": 10, "0b02": 20, "0a01_0b02": 5}},
+			wantBalanced: true, wantAccounts: 2, wantAllowance: 1,
+		},
+		{
+			name:         "supply mismatch",
+			token:        Token{Total: 40, Balance: map[string]uint64{"0a01": 10, "0b02": 20}},
+			wantAccounts: 2,
+		},
+		{
+			name:         "unreachable balances",
+			token:        Token{Total: 30, Balance: map[string]uint64{"": 10, "alice": 10, "0a01_x": 10}},
+			wantBalanced: true, wantAccounts: 3, wantIssues: 3,
+		},
+		{
+			name:         "escrow counts toward the supply",
+			token:        Token{Total: 30, Escrow: 5, Balance: map[string]uint64{"0a01": 5, "0b02": 20}},
+			wantBalanced: true, wantAccounts: 2,
+		},
+		{
+			name:         "overflow",
+			token:        Token{Total: math.MaxUint64, Balance: map[string]uint64{"0a01": math.MaxUint64, "0b02": 1}},
+			wantAccounts: 2, wantIssues: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := reconcile(&tt.token)
+			if report.Balanced != tt.wantBalanced || report.Accounts != tt.wantAccounts || report.Allowances != tt.wantAllowance || len(report.Discrepancies) != tt.wantIssues {
+				t.Errorf("reconcile() = %+v", report)
+			}
+		})
+	}
+}
+
+func TestGovernorTermsAdmits(t *testing.T) {
+	terms := &GovernorTerms{SignerGroup: "payroll", MinApproval: 2}
+	tests := []struct {
+		name     string
+		terms    *GovernorTerms
+		approval access.Approval
+		want     bool
+	}{
+		{name: "on terms", terms: terms, approval: access.Approval{SignerGroup: "payroll", YesWeight: 2}, want: true},
+		{name: "more than the minimum", terms: terms, approval: access.Approval{SignerGroup: "payroll", YesWeight: 5}, want: true},
+		{name: "too little weight", terms: terms, approval: access.Approval{SignerGroup: "payroll", YesWeight: 1}},
+		{name: "other group", terms: terms, approval: access.Approval{SignerGroup: "staff", YesWeight: 9}},
+		{name: "no terms", approval: access.Approval{SignerGroup: "payroll", YesWeight: 9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.terms.admits(&tt.approval); got != tt.want {
+				t.Errorf("admits(%+v) = %v, want %v", tt.approval, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,401 @@
+package token
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// standingOrderPrefix is the composite key object type for standing orders
+const standingOrderPrefix = "standingorder"
+
+// dueOrderPrefix starts the keys of the due order index, which lists every
+// active order under its next run as dueorder~<zero-padded next run>~<order ID>.
+// Fabric cannot range over composite keys, so the index uses simple keys, and
+// ExecuteDueOrders reads only the orders due by its transaction time
+const dueOrderPrefix = "dueorder~"
+
+// Standing order states
+const (
+	OrderActive    = "active"
+	OrderCompleted = "completed"
+	OrderCancelled = "cancelled"
+)
+
+// maxOrderInterval bounds the interval of a standing order to a year
+const maxOrderInterval = 366 * 24 * 60 * 60
+
+// StandingOrder is a recurring payment the payer authorises to be pulled
+// from their balance every Interval seconds between StartTime and EndTime
+type StandingOrder struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	ID            string         `json:"id"`
+	Payer         string         `json:"payer"`
+	Payee         string         `json:"payee"`
+	Amount        uint64         `json:"amount"`
+	Interval      int64          `json:"interval"`
+	StartTime     int64          `json:"startTime"`
+	EndTime       int64          `json:"endTime"`
+	NextRun       int64          `json:"nextRun"`
+	Status        string         `json:"status"`
+	Payments      uint64         `json:"payments"`
+	Failures      []OrderFailure `json:"failures"`
+}
+
+// OrderFailure records a scheduled payment that could not be made
+type OrderFailure struct {
+	Due    int64  `json:"due"`
+	TxID   string `json:"txId"`
+	Reason string `json:"reason"`
+}
+
+// executionSummary is returned by ExecuteDueOrders and emitted as its event
+type executionSummary struct {
+	Timestamp int64    `json:"timestamp"`
+	Executed  []string `json:"executed"`
+	Failed    []string `json:"failed"`
+}
+
+// nextRunAfter returns the first run of a schedule that runs at nextRun and then
+// every interval seconds that falls after now. ok is false if that run is too
+// far in the future to represent
+func nextRunAfter(nextRun int64, interval int64, now int64) (next int64, ok bool) {
+	if nextRun > now {
+		return nextRun, true
+	}
+	periods := (now-nextRun)/interval + 1
+	if periods > (math.MaxInt64-nextRun)/interval {
+		return 0, false
+	}
+	return nextRun + periods*interval, true
+}
+
+func getStandingOrder(stub shim.ChaincodeStubInterface, orderID string) (*StandingOrder, error) {
+	key, err := stub.CreateCompositeKey(standingOrderPrefix, []string{orderID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create key: %s", err)
+	}
+	orderJSON, err := stub.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get standing order: %s", err)
+	}
+	if orderJSON == nil {
+		return nil, errcode.Newf(errcode.NotFoundCode, "Standing order does not exist: %s", orderID)
+	}
+	var order StandingOrder
+	err = json.Unmarshal(orderJSON, &order)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal standing order: %s", err)
+	}
+	return &order, nil
+}
+
+// dueOrderKey returns the due order index key of an order that next runs at nextRun
+func dueOrderKey(nextRun int64, orderID string) string {
+	return fmt.Sprintf("%s%019d~%s", dueOrderPrefix, nextRun, orderID)
+}
+
+// indexDueOrder moves the due order index entry of an order from its previous
+// state, nil for a new order, to its current one. Only active orders are listed
+func indexDueOrder(stub shim.ChaincodeStubInterface, previous *StandingOrder, order *StandingOrder) error {
+	if previous != nil && previous.Status == OrderActive {
+		if order.Status == OrderActive && order.NextRun == previous.NextRun {
+			return nil
+		}
+		err := stub.DelState(dueOrderKey(previous.NextRun, previous.ID))
+		if err != nil {
+			return fmt.Errorf("Failed to delete index entry: %s", err)
+		}
+	}
+	if order.Status != OrderActive {
+		return nil
+	}
+	err := stub.PutState(dueOrderKey(order.NextRun, order.ID), []byte(order.ID))
+	if err != nil {
+		return fmt.Errorf("Failed to put index entry: %s", err)
+	}
+	return nil
+}
+
+// reindexStandingOrder adds a stored active order to the due order index.
+// Migrate calls it for orders stored before the index existed
+func reindexStandingOrder(stub shim.ChaincodeStubInterface, key string, value []byte) error {
+	var order StandingOrder
+	err := json.Unmarshal(value, &order)
+	if err != nil {
+		return fmt.Errorf("Failed to unmarshal standing order: %s", err)
+	}
+	return indexDueOrder(stub, nil, &order)
+}
+
+// standingOrderIndexed reports whether a stored order is in the due order index
+// if it is active, so Migrate only reindexes orders that are missing
+func standingOrderIndexed(stub shim.ChaincodeStubInterface, key string, value []byte) (bool, error) {
+	var order StandingOrder
+	err := json.Unmarshal(value, &order)
+	if err != nil {
+		return false, fmt.Errorf("Failed to unmarshal standing order: %s", err)
+	}
+	if order.Status != OrderActive {
+		return true, nil
+	}
+	entry, err := stub.GetState(dueOrderKey(order.NextRun, order.ID))
+	if err != nil {
+		return false, fmt.Errorf("Failed to get index entry: %s", err)
+	}
+	return entry != nil, nil
+}
+
+// putStandingOrder saves a standing order and keeps its due order index entry current
+func putStandingOrder(stub shim.ChaincodeStubInterface, order *StandingOrder) error {
+	key, err := stub.CreateCompositeKey(standingOrderPrefix, []string{order.ID})
+	if err != nil {
+		return fmt.Errorf("Failed to create key: %s", err)
+	}
+	var previous *StandingOrder
+	previousJSON, err := stub.GetState(key)
+	if err != nil {
+		return fmt.Errorf("Failed to get standing order: %s", err)
+	} else if previousJSON != nil {
+		previous = &StandingOrder{}
+		err = json.Unmarshal(previousJSON, previous)
+		if err != nil {
+			return fmt.Errorf("Failed to unmarshal standing order: %s", err)
+		}
+	}
+	err = indexDueOrder(stub, previous, order)
+	if err != nil {
+		return err
+	}
+
+	orderJSON, err := json.Marshal(order)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal standing order: %s", err)
+	}
+	err = stub.PutState(key, orderJSON)
+	if err != nil {
+		return fmt.Errorf("Failed to put state: %s", err)
+	}
+	return nil
+}
+
+// CreateStandingOrder authorises repeated payments of amount from the caller to
+// the recipient every interval seconds, starting at startTime and ending at
+// endTime (Unix seconds, 0 for no end). Returns the order ID
+func (t *TokenERC20Chaincode) CreateStandingOrder(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	// Check number of arguments
+	if len(args) != 5 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 5: to address, amount, interval, start time, end time")
+	}
+
+	if args[0] == "" {
+		return errcode.InvalidArgument("Recipient address must be a non-empty string")
+	}
+	payee, err := resolveAccount(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	amount, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil || amount == 0 {
+		return errcode.InvalidArgument("Amount must be a positive integer")
+	}
+	interval, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil || interval <= 0 || interval > maxOrderInterval {
+		return errcode.InvalidArgument(fmt.Sprintf("Interval must be between 1 and %d seconds", maxOrderInterval))
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	startTime, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil || startTime < now {
+		return errcode.InvalidArgument("Start time must not be before the transaction time")
+	}
+	endTime, err := strconv.ParseInt(args[4], 10, 64)
+	if err != nil || endTime < 0 {
+		return errcode.InvalidArgument("Invalid end time")
+	}
+	if endTime != 0 && endTime < startTime {
+		return errcode.InvalidArgument("End time must not be before start time")
+	}
+
+	// The payer is the transaction creator
+	payer, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
+	}
+	payerHex := hex.EncodeToString(payer)
+	if payerHex == payee {
+		return errcode.InvalidArgument("Payer cannot create a standing order to their own account")
+	}
+
+	order := StandingOrder{
+		SchemaVersion: standingOrderVersion,
+		ID:            stub.GetTxID(),
+		Payer:         payerHex,
+		Payee:         payee,
+		Amount:        amount,
+		Interval:      interval,
+		StartTime:     startTime,
+		EndTime:       endTime,
+		NextRun:       startTime,
+		Status:        OrderActive,
+		Failures:      []OrderFailure{},
+	}
+	err = putStandingOrder(stub, &order)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	return shim.Success([]byte(order.ID))
+}
+
+// CancelStandingOrder stops all future payments of an order. Only the payer can cancel
+func (t *TokenERC20Chaincode) CancelStandingOrder(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1: order ID")
+	}
+
+	order, err := getStandingOrder(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	caller, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
+	}
+	if hex.EncodeToString(caller) != order.Payer {
+		return errcode.Forbidden("Only the payer can cancel a standing order")
+	}
+	if order.Status != OrderActive {
+		return errcode.Conflict(fmt.Sprintf("Standing order is already %s", order.Status))
+	}
+
+	order.Status = OrderCancelled
+	err = putStandingOrder(stub, order)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	return shim.Success(nil)
+}
+
+// GetStandingOrder returns a standing order with its payment and failure history
+func (t *TokenERC20Chaincode) GetStandingOrder(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1: order ID")
+	}
+
+	order, err := getStandingOrder(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	orderJSON, err := json.Marshal(order)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to marshal standing order: %s", err))
+	}
+
+	return shim.Success(orderJSON)
+}
+
+// ExecuteDueOrders makes one payment for every active order that is due at the
+// transaction timestamp, however many periods passed since it was last due, in
+// the order they fell due. Orders whose payer has insufficient funds are skipped
+// for this period and the failure is recorded on the order.
+// Any keeper identity may invoke it. This function triggers a StandingOrdersExecuted event
+func (t *TokenERC20Chaincode) ExecuteDueOrders(stub shim.ChaincodeStubInterface) pb.Response {
+	now, err := txTime(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	token, err := getToken(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	// The due order index lists only active orders, by next run, so the
+	// range ends with the orders due at now
+	resultsIterator, err := stub.GetStateByRange(dueOrderPrefix, dueOrderKey(now+1, ""))
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to query due orders: %s", err))
+	}
+	defer resultsIterator.Close()
+
+	// Collect due orders first so the iterator is not held open across writes
+	var due []*StandingOrder
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return errcode.FromError(err)
+		}
+		order, err := getStandingOrder(stub, string(queryResponse.Value))
+		if err != nil {
+			return errcode.FromError(err)
+		}
+		due = append(due, order)
+	}
+
+	summary := executionSummary{Timestamp: now, Executed: []string{}, Failed: []string{}}
+	for i, order := range due {
+		if order.EndTime != 0 && order.NextRun > order.EndTime {
+			order.Status = OrderCompleted
+		} else if token.Balance[order.Payer] < order.Amount {
+			order.Failures = append(order.Failures, OrderFailure{
+				Due:    order.NextRun,
+				TxID:   stub.GetTxID(),
+				Reason: "Insufficient balance",
+			})
+			summary.Failed = append(summary.Failed, order.ID)
+		} else {
+			token.Balance[order.Payer] -= order.Amount
+			token.Balance[order.Payee] += order.Amount
+			order.Payments++
+			summary.Executed = append(summary.Executed, order.ID)
+
+			// Each payment gets its own transfer record so it can be refunded or disputed
+			transferID := fmt.Sprintf("%s.%d", stub.GetTxID(), i)
+			_, err = recordTransfer(stub, transferID, order.Payer, order.Payee, order.Amount, "Standing order "+order.ID, nil)
+			if err != nil {
+				return errcode.FromError(err)
+			}
+		}
+
+		// One payment per call; the periods missed since NextRun are skipped,
+		// not retried, so the next run is the first one after now
+		if order.Status == OrderActive {
+			next, ok := nextRunAfter(order.NextRun, order.Interval, now)
+			order.NextRun = next
+			if !ok || (order.EndTime != 0 && order.NextRun > order.EndTime) {
+				order.Status = OrderCompleted
+			}
+		}
+		err = putStandingOrder(stub, order)
+		if err != nil {
+			return errcode.FromError(err)
+		}
+	}
+
+	err = putToken(stub, token)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	summaryJSON, err := json.Marshal(summary)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to marshal summary: %s", err))
+	}
+	err = stub.SetEvent("StandingOrdersExecuted", summaryJSON)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to set event: %s", err))
+	}
+
+	return shim.Success(summaryJSON)
+}
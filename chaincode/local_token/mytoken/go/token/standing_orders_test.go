@@ -0,0 +1,61 @@
+package token
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNextRunAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		nextRun  int64
+		interval int64
+		now      int64
+		want     int64
+		wantOK   bool
+	}{
+		{name: "not yet due", nextRun: 200, interval: 60, now: 100, want: 200, wantOK: true},
+		{name: "due now", nextRun: 100, interval: 60, now: 100, want: 160, wantOK: true},
+		{name: "due a moment ago", nextRun: 100, interval: 60, now: 101, want: 160, wantOK: true},
+		{name: "several periods missed", nextRun: 100, interval: 60, now: 400, want: 460, wantOK: true},
+		{name: "exactly on a later run", nextRun: 100, interval: 60, now: 220, want: 280, wantOK: true},
+		{name: "last representable run", nextRun: math.MaxInt64 - 10, interval: 10, now: math.MaxInt64 - 10, want: math.MaxInt64, wantOK: true},
+		{name: "beyond int64", nextRun: math.MaxInt64 - 10, interval: 20, now: math.MaxInt64 - 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := nextRunAfter(tt.nextRun, tt.interval, tt.now)
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("nextRunAfter() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+			if ok && got <= tt.now {
+				t.Errorf("nextRunAfter() = %d, not after %d", got, tt.now)
+			}
+		})
+	}
+}
+
+func TestDueOrderKeyRange(t *testing.T) {
+	const now = 1000
+	end := dueOrderKey(now+1, "")
+	tests := []struct {
+		name    string
+		nextRun int64
+		due     bool
+	}{
+		{name: "long overdue", nextRun: 5, due: true},
+		{name: "due a moment ago", nextRun: now - 1, due: true},
+		{name: "due now", nextRun: now, due: true},
+		{name: "due next second", nextRun: now + 1},
+		{name: "due much later", nextRun: 100000},
+		{name: "last representable run", nextRun: math.MaxInt64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := dueOrderKey(tt.nextRun, "order~1")
+			if inRange := key >= dueOrderPrefix && key < end; inRange != tt.due {
+				t.Errorf("%q in [%q, %q) = %v, want %v", key, dueOrderPrefix, end, inRange, tt.due)
+			}
+		})
+	}
+}
@@ -0,0 +1,550 @@
+package token
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Composite key object types for transfer records and the per-account statement index
+const (
+	transferPrefix        = "transfer"
+	accountTransferPrefix = "account~transfer"
+)
+
+// Size limits for transfer memos and references, in bytes
+const (
+	maxMemoLength      = 256
+	maxReferenceLength = 1024
+)
+
+// defaultDisputeWindow is used until SetDisputeWindow is called (7 days)
+const defaultDisputeWindow = 7 * 24 * 60 * 60
+
+// Transfer record states
+const (
+	TransferCompleted         = "completed"
+	TransferPartiallyRefunded = "partially_refunded"
+	TransferRefunded          = "refunded"
+)
+
+// Dispute states and resolutions
+const (
+	DisputeOpen     = "open"
+	DisputeUpheld   = "upheld"
+	DisputeRejected = "rejected"
+)
+
+// TransferRecord is stored for every transfer so it can later be refunded or disputed
+type TransferRecord struct {
+	SchemaVersion int                `json:"schemaVersion"`
+	ID            string             `json:"id"`
+	From          string             `json:"from"`
+	To            string             `json:"to"`
+	Amount        uint64             `json:"amount"`
+	Refunded      uint64             `json:"refunded"`
+	Timestamp     int64              `json:"timestamp"`
+	Status        string             `json:"status"`
+	Memo          string             `json:"memo,omitempty"`
+	Reference     *TransferReference `json:"reference,omitempty"`
+	Refunds       []Refund           `json:"refunds"`
+	Dispute       *Dispute           `json:"dispute,omitempty"`
+}
+
+// TransferReference is the structured reference accountants attach to a payment
+type TransferReference struct {
+	InvoiceID  string `json:"invoiceId,omitempty"`
+	CostCentre string `json:"costCentre,omitempty"`
+	PayrollRun string `json:"payrollRun,omitempty"`
+}
+
+// parseMemo validates the optional memo and JSON reference arguments of a transfer
+func parseMemo(args []string) (string, *TransferReference, error) {
+	memo := ""
+	if len(args) > 0 {
+		memo = args[0]
+	}
+	if len(memo) > maxMemoLength {
+		return "", nil, errcode.Newf(errcode.InvalidArgumentCode, "Memo must be at most %d bytes", maxMemoLength)
+	}
+	if len(args) < 2 || args[1] == "" {
+		return memo, nil, nil
+	}
+
+	if len(args[1]) > maxReferenceLength {
+		return "", nil, errcode.Newf(errcode.InvalidArgumentCode, "Reference must be at most %d bytes", maxReferenceLength)
+	}
+	var reference TransferReference
+	decoder := json.NewDecoder(bytes.NewReader([]byte(args[1])))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&reference)
+	if err != nil {
+		return "", nil, errcode.Newf(errcode.InvalidArgumentCode, "Invalid reference, expecting JSON with invoiceId, costCentre and/or payrollRun: %s", err)
+	}
+	return memo, &reference, nil
+}
+
+// matches reports whether the record's memo or reference contains the search text
+func (r *TransferRecord) matches(search string) bool {
+	search = strings.ToLower(search)
+	fields := []string{r.Memo}
+	if r.Reference != nil {
+		fields = append(fields, r.Reference.InvoiceID, r.Reference.CostCentre, r.Reference.PayrollRun)
+	}
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), search) {
+			return true
+		}
+	}
+	return false
+}
+
+// Refund is a voluntary return of funds by the recipient
+type Refund struct {
+	TxID      string `json:"txId"`
+	Amount    uint64 `json:"amount"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Dispute is raised by the sender and resolved by a manager-org arbiter. Held
+// is the amount taken from the recipient into escrow while the dispute is open
+type Dispute struct {
+	Reason     string `json:"reason"`
+	OpenedAt   int64  `json:"openedAt"`
+	Status     string `json:"status"`
+	Held       uint64 `json:"held"`
+	Arbiter    string `json:"arbiter,omitempty"`
+	ResolvedAt int64  `json:"resolvedAt,omitempty"`
+	Amount     uint64 `json:"amount,omitempty"`
+}
+
+// remaining returns the amount of the transfer that has not been returned yet
+func (r *TransferRecord) remaining() uint64 {
+	return r.Amount - r.Refunded
+}
+
+// applyRefund moves amount back from the recipient to the sender
+func (r *TransferRecord) applyRefund(token *Token, amount uint64) error {
+	if amount == 0 || amount > r.remaining() {
+		return errcode.Newf(errcode.InvalidArgumentCode, "Refund amount must be between 1 and %d", r.remaining())
+	}
+	if token.Balance[r.To] < amount {
+		return errcode.New(errcode.InsufficientFundsCode, "Insufficient balance")
+	}
+	token.Balance[r.To] -= amount
+	token.Balance[r.From] += amount
+	r.Refunded += amount
+	if r.Refunded == r.Amount {
+		r.Status = TransferRefunded
+	} else {
+		r.Status = TransferPartiallyRefunded
+	}
+	return nil
+}
+
+// hold moves the disputed amount from the recipient into escrow: what has not
+// been returned yet, or as much of it as the recipient still holds
+func (r *TransferRecord) hold(token *Token) {
+	held := r.remaining()
+	if balance := token.Balance[r.To]; balance < held {
+		held = balance
+	}
+	token.Balance[r.To] -= held
+	token.Escrow += held
+	r.Dispute.Held = held
+}
+
+// release returns the amount held for the dispute to the recipient
+func (r *TransferRecord) release(token *Token) {
+	token.Escrow -= r.Dispute.Held
+	token.Balance[r.To] += r.Dispute.Held
+}
+
+func getTransferRecord(stub shim.ChaincodeStubInterface, transferID string) (*TransferRecord, error) {
+	key, err := stub.CreateCompositeKey(transferPrefix, []string{transferID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create key: %s", err)
+	}
+	recordJSON, err := stub.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get transfer: %s", err)
+	}
+	if recordJSON == nil {
+		return nil, errcode.Newf(errcode.NotFoundCode, "Transfer does not exist: %s", transferID)
+	}
+	var record TransferRecord
+	err = json.Unmarshal(recordJSON, &record)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal transfer: %s", err)
+	}
+	return &record, nil
+}
+
+func putTransferRecord(stub shim.ChaincodeStubInterface, record *TransferRecord) error {
+	key, err := stub.CreateCompositeKey(transferPrefix, []string{record.ID})
+	if err != nil {
+		return fmt.Errorf("Failed to create key: %s", err)
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal transfer: %s", err)
+	}
+	err = stub.PutState(key, recordJSON)
+	if err != nil {
+		return fmt.Errorf("Failed to put state: %s", err)
+	}
+	return nil
+}
+
+// recordTransfer stores a new transfer record with the given ID and indexes it
+// under both accounts for statements
+func recordTransfer(stub shim.ChaincodeStubInterface, transferID, from, to string, amount uint64, memo string, reference *TransferReference) (*TransferRecord, error) {
+	now, err := txTime(stub)
+	if err != nil {
+		return nil, err
+	}
+	record := TransferRecord{
+		SchemaVersion: transferVersion,
+		ID:            transferID,
+		From:          from,
+		To:            to,
+		Amount:        amount,
+		Timestamp:     now,
+		Status:        TransferCompleted,
+		Memo:          memo,
+		Reference:     reference,
+		Refunds:       []Refund{},
+	}
+	err = putTransferRecord(stub, &record)
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range []string{from, to} {
+		indexKey, err := stub.CreateCompositeKey(accountTransferPrefix, []string{account, transferID})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create key: %s", err)
+		}
+		err = stub.PutState(indexKey, []byte{0x00})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to put state: %s", err)
+		}
+	}
+	return &record, nil
+}
+
+// setRecordEvent emits the given event with the transfer record as payload
+func setRecordEvent(stub shim.ChaincodeStubInterface, name string, record *TransferRecord) error {
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal transfer: %s", err)
+	}
+	err = stub.SetEvent(name, recordJSON)
+	if err != nil {
+		return fmt.Errorf("Failed to set event: %s", err)
+	}
+	return nil
+}
+
+// getDisputeWindow returns how long after a transfer the sender may dispute it, in seconds
+func getDisputeWindow(stub shim.ChaincodeStubInterface) (int64, error) {
+	windowBytes, err := stub.GetState("disputeWindow")
+	if err != nil {
+		return 0, fmt.Errorf("Failed to get dispute window: %s", err)
+	}
+	if windowBytes == nil {
+		return defaultDisputeWindow, nil
+	}
+	return strconv.ParseInt(string(windowBytes), 10, 64)
+}
+
+// GetTransfer returns a transfer record with its refunds and dispute
+func (t *TokenERC20Chaincode) GetTransfer(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1: transfer ID")
+	}
+
+	record, err := getTransferRecord(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to marshal transfer: %s", err))
+	}
+
+	return shim.Success(recordJSON)
+}
+
+// GetStatement returns the transfers sent or received by an account, oldest first.
+// The optional search text is matched against memos and reference fields
+func (t *TokenERC20Chaincode) GetStatement(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 && len(args) != 2 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1 or 2: account and optional search text")
+	}
+	if args[0] == "" {
+		return errcode.InvalidArgument("Account must be a non-empty string")
+	}
+	account, err := resolveAccount(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	search := ""
+	if len(args) == 2 {
+		search = args[1]
+	}
+
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(accountTransferPrefix, []string{account})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to query transfers: %s", err))
+	}
+	defer resultsIterator.Close()
+
+	records := []*TransferRecord{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return errcode.FromError(err)
+		}
+		_, keyParts, err := stub.SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return errcode.Internal(fmt.Sprintf("Failed to split key: %s", err))
+		}
+		record, err := getTransferRecord(stub, keyParts[1])
+		if err != nil {
+			return errcode.FromError(err)
+		}
+		if search == "" || record.matches(search) {
+			records = append(records, record)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp < records[j].Timestamp
+	})
+
+	recordsJSON, err := json.Marshal(records)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to marshal transfers: %s", err))
+	}
+
+	return shim.Success(recordsJSON)
+}
+
+// Refund returns part or all of a received transfer to its sender.
+// Only the recipient can refund. This function triggers a Refund event
+func (t *TokenERC20Chaincode) Refund(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 2: transfer ID and amount")
+	}
+
+	amount, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return errcode.InvalidArgument(fmt.Sprintf("Invalid amount: %s", err))
+	}
+	record, err := getTransferRecord(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	caller, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
+	}
+	if hex.EncodeToString(caller) != record.To {
+		return errcode.Forbidden("Only the recipient can refund a transfer")
+	}
+
+	token, err := getToken(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	err = record.applyRefund(token, amount)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	record.Refunds = append(record.Refunds, Refund{TxID: stub.GetTxID(), Amount: amount, Timestamp: now})
+
+	err = putToken(stub, token)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	err = putTransferRecord(stub, record)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	err = setRecordEvent(stub, "Refund", record)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	return shim.Success(nil)
+}
+
+// OpenDispute lets the sender contest a transfer within the dispute window. The
+// disputed amount is held in escrow, out of the recipient's reach, until the
+// dispute is resolved. This function triggers a DisputeOpened event
+func (t *TokenERC20Chaincode) OpenDispute(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 2: transfer ID and reason")
+	}
+	if args[1] == "" {
+		return errcode.InvalidArgument("Reason must be a non-empty string")
+	}
+
+	record, err := getTransferRecord(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	caller, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
+	}
+	if hex.EncodeToString(caller) != record.From {
+		return errcode.Forbidden("Only the sender can dispute a transfer")
+	}
+	if record.Dispute != nil {
+		return errcode.Conflict("Transfer has already been disputed")
+	}
+	if record.remaining() == 0 {
+		return errcode.Conflict("Transfer has been fully refunded")
+	}
+
+	now, err := txTime(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	window, err := getDisputeWindow(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	if now > record.Timestamp+window {
+		return errcode.Conflict("Dispute window has closed")
+	}
+
+	token, err := getToken(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	record.Dispute = &Dispute{Reason: args[1], OpenedAt: now, Status: DisputeOpen}
+	record.hold(token)
+	err = putToken(stub, token)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	err = putTransferRecord(stub, record)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	err = setRecordEvent(stub, "DisputeOpened", record)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	return shim.Success(nil)
+}
+
+// ResolveDispute closes an open dispute and releases the escrow. Decision
+// "uphold" returns amount to the sender and the rest of the escrow to the
+// recipient, "reject" returns the escrow to the recipient and takes no amount.
+// Only manager-org arbiters can resolve. This function triggers a DisputeResolved event
+func (t *TokenERC20Chaincode) ResolveDispute(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 && len(args) != 3 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 2 or 3: transfer ID, decision (uphold/reject) and amount to uphold")
+	}
+
+	decision := args[1]
+	if decision != "uphold" && decision != "reject" {
+		return errcode.InvalidArgument("Invalid decision. Expecting \"uphold\" or \"reject\"")
+	}
+
+	record, err := getTransferRecord(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	if record.Dispute == nil || record.Dispute.Status != DisputeOpen {
+		return errcode.Conflict("Transfer has no open dispute")
+	}
+
+	arbiter, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to get creator: %s", err))
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	token, err := getToken(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	// Disputes opened before escrow existed hold nothing, so an upheld refund
+	// is paid from what the recipient holds, as it was then
+	record.release(token)
+	if decision == "uphold" {
+		if len(args) < 3 || args[2] == "" {
+			return errcode.InvalidArgument("Amount is required to uphold a dispute")
+		}
+		amount, err := strconv.ParseUint(args[2], 10, 64)
+		if err != nil {
+			return errcode.InvalidArgument(fmt.Sprintf("Invalid amount: %s", err))
+		}
+		err = record.applyRefund(token, amount)
+		if err != nil {
+			return errcode.FromError(err)
+		}
+		record.Dispute.Status = DisputeUpheld
+		record.Dispute.Amount = amount
+	} else {
+		record.Dispute.Status = DisputeRejected
+	}
+	err = putToken(stub, token)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	record.Dispute.Arbiter = hex.EncodeToString(arbiter)
+	record.Dispute.ResolvedAt = now
+
+	err = putTransferRecord(stub, record)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	err = setRecordEvent(stub, "DisputeResolved", record)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	return shim.Success(nil)
+}
+
+// SetDisputeWindow sets how many seconds after a transfer the sender may open a dispute.
+// Only manager-org members can change it
+func (t *TokenERC20Chaincode) SetDisputeWindow(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1: window in seconds")
+	}
+
+	window, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || window < 0 {
+		return errcode.InvalidArgument("Window must be a non-negative number of seconds")
+	}
+
+	err = stub.PutState("disputeWindow", []byte(strconv.FormatInt(window, 10)))
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to put state: %s", err))
+	}
+
+	return shim.Success(nil)
+}
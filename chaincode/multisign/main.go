@@ -0,0 +1,15 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/chaincode/lib/launch"
+	"github.com/chaincode/multisign/multisign"
+)
+
+func main() {
+	err := launch.Start(new(multisign.MultisignChaincode))
+	if err != nil {
+		fmt.Printf("Error starting MultisignChaincode: %s", err)
+	}
+}
@@ -0,0 +1,63 @@
+package multisign
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// isAccountID reports whether s is a hex-encoded identity. Aliases without an
+// @org suffix are at most 32 characters, so shorter hex strings may be aliases
+func isAccountID(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil && len(s) > 32
+}
+
+// resolveAlias asks tokenChaincode for the account an alias is registered to,
+// and returns "" if it is not registered
+func resolveAlias(stub shim.ChaincodeStubInterface, alias string) (string, error) {
+	response := stub.InvokeChaincode(tokenChaincode, [][]byte{[]byte("ResolveAlias"), []byte(alias)}, "")
+	if response.Status < shim.ERRORTHRESHOLD {
+		return string(response.Payload), nil
+	}
+	failure := errcode.Parse(response)
+	if failure.Code == errcode.NotFoundCode {
+		return "", nil
+	}
+	failure.Message = fmt.Sprintf("ResolveAlias in %s failed: %s", tokenChaincode, failure.Message)
+	return "", failure
+}
+
+// resolveAccount returns the account ID of an account ID or alias. Requests
+// store and index accounts by ID, so each account is found under one key
+func resolveAccount(stub shim.ChaincodeStubInterface, account string) (string, error) {
+	account = strings.ToLower(account)
+	if isAccountID(account) {
+		return account, nil
+	}
+	resolved, err := resolveAlias(stub, account)
+	if err != nil {
+		return "", err
+	}
+	if resolved == "" {
+		return "", errcode.Newf(errcode.NotFoundCode, "Unknown account or alias: %s", account)
+	}
+	return resolved, nil
+}
+
+// resolveTarget returns what the target index lists a target filter under: the
+// account ID of an account or alias, or else the chaincode name of call requests
+func resolveTarget(stub shim.ChaincodeStubInterface, target string) (string, error) {
+	lower := strings.ToLower(target)
+	if isAccountID(lower) {
+		return lower, nil
+	}
+	account, err := resolveAlias(stub, lower)
+	if err != nil || account != "" {
+		return account, err
+	}
+	return target, nil
+}
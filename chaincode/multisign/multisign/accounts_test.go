@@ -0,0 +1,39 @@
+package multisign
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsAccountID(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want bool
+	}{
+		{name: "account ID", s: strings.Repeat("0a", 40), want: true},
+		{name: "short hex", s: "beef", want: false},
+		{name: "hex-shaped alias", s: strings.Repeat("ab", 16), want: false},
+		{name: "alias", s: "user8@orgstaff", want: false},
+		{name: "odd length", s: strings.Repeat("a", 41), want: false},
+		{name: "empty", s: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAccountID(tt.s); got != tt.want {
+				t.Errorf("isAccountID(%q) = %v, want %v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestTarget(t *testing.T) {
+	transfer := Request{Type: TransferRequest, TargetAccount: "aa"}
+	if got := transfer.target(); got != "aa" {
+		t.Errorf("transfer target = %q, want aa", got)
+	}
+	call := Request{Type: CallRequest, Call: &Call{Chaincode: "token_erc20", Function: "Mint"}}
+	if got := call.target(); got != "token_erc20" {
+		t.Errorf("call target = %q, want token_erc20", got)
+	}
+}
@@ -0,0 +1,179 @@
+package multisign
+
+import (
+	"github.com/chaincode/lib/access"
+	"github.com/chaincode/lib/contract"
+	"github.com/chaincode/lib/migrate"
+)
+
+// managerRule admits the manager org, which governs approval rules, or a
+// proposal the board approved
+var managerRule = access.Rule{MSPIDs: []string{access.ManagerMSP}}.OrGovernedBy(access.Board)
+
+// thresholdArg describes a threshold argument
+func thresholdArg(optional bool, description string) contract.Arg {
+	if description != "" {
+		description = ". " + description
+	}
+	return contract.Arg{
+		Name:        "threshold",
+		Type:        "string",
+		Format:      "json",
+		Optional:    optional,
+		Description: `In vote weight: {"required":M} for a yes weight of M, or {"percent":P,"quorum":Q} for P% of the yes and no weight once signers weighing Q voted` + description,
+	}
+}
+
+// catalogue is the function catalogue of the multisign chaincode. Invoke
+// dispatches through it and GetMetadata publishes it
+func (t *MultisignChaincode) catalogue() *contract.Contract {
+	requestIDArg := contract.Arg{Name: "requestId", Type: "string"}
+	newRequestIDArg := contract.Arg{Name: "requestId", Type: "string", Optional: true, MaxLength: 128, Description: "ID of the new request, which must not exist yet. Empty for the transaction ID"}
+	votingPeriodArg := contract.Arg{Name: "votingPeriod", Type: "integer", Optional: true, Description: "Seconds the request stays open for votes, default 7 days"}
+	pageSizeArg := contract.Arg{Name: "pageSize", Type: "integer", Optional: true, Description: "Records per page, 1 to 1000, default 100"}
+	bookmarkArg := contract.Arg{Name: "bookmark", Type: "string", Optional: true, Description: "Bookmark from the previous page"}
+	signersArg := contract.Arg{Name: "signers", Type: "string", Description: "Name of a signer group set with SetSignerGroup, or a JSON array of account IDs that are all members of one. A list is decided for the first such group, by name, by the listed members alone"}
+	signerGroupArg := contract.Arg{Name: "name", Type: "string", Pattern: `^[A-Za-z0-9_.-]+$`, MaxLength: 64, Description: "Signer group name"}
+	return &contract.Contract{
+		Name:        "multisign",
+		Description: "Requests for token transfers or chaincode calls that a set of signers approve or reject by vote",
+		Functions: append([]contract.Function{
+			{
+				Name:        "submitRequest",
+				Description: "Asks the signers to approve a transfer of amount from sourceAccount to targetAccount. The requester is never one of the signers. Emits RequestSubmitted",
+				Args: []contract.Arg{
+					newRequestIDArg,
+					{Name: "targetAccount", Type: "string", Format: "account"},
+					{Name: "amount", Type: "integer", Description: "Amount in the token's smallest unit"},
+					{Name: "sourceAccount", Type: "string", Format: "account", Description: "Treasury account that approved this chaincode with ApproveGovernor in " + tokenChaincode},
+					signersArg,
+					thresholdArg(true, "Defaults to the channel default, else 2/3 of the signers' weight"),
+					votingPeriodArg,
+				},
+				Returns: "text request ID",
+				Handler: t.submitRequest,
+			},
+			{
+				Name:        "submitProposal",
+				Description: "Asks the signers to approve a call of a chaincode function whose rule trusts proposals approved here. The requester finalizes it and the response is stored on the request. Emits RequestSubmitted",
+				Args: []contract.Arg{
+					newRequestIDArg,
+					{Name: "chaincode", Type: "string"},
+					{Name: "function", Type: "string"},
+					{Name: "args", Type: "string", Format: "json", Description: "JSON array of string arguments"},
+					signersArg,
+					thresholdArg(true, "Defaults to the channel default, else 2/3 of the signers' weight"),
+					{Name: "channel", Type: "string", Optional: true, Description: "Defaults to this channel. Calls to another channel can only read"},
+					votingPeriodArg,
+				},
+				Returns: "text request ID",
+				Handler: t.submitProposal,
+			},
+			{
+				Name:        "respondToRequest",
+				Description: "Records or changes the caller's vote while the request is Pending, keeping earlier votes in its history. Only signers of the request may vote, until its deadline. An abstention counts toward a quorum but not toward approval. Votes never close a request; finalizeRequest settles it. Emits VoteCast",
+				Args: []contract.Arg{
+					requestIDArg,
+					{Name: "response", Type: "string", Enum: []string{VoteYes, VoteNo, VoteAbstain}},
+					{Name: "comment", Type: "string", Optional: true, MaxLength: 1024, Description: "Reason for the vote"},
+				},
+				Returns: "nothing",
+				Handler: t.respondToRequest,
+			},
+			{
+				Name:        "evaluateRequest",
+				Description: "Counts the votes cast so far",
+				Args:        []contract.Arg{requestIDArg},
+				Returns:     "text \"Total responses: N, Yes responses: M, Signers: S, Voted weight: V, Yes weight: Y, Total weight: T\"",
+				ReadOnly:    true,
+				Handler:     t.evaluateRequest,
+			},
+			{
+				Name:        "finalizeRequest",
+				Description: "Settles the request: executes it once its votes pass the threshold, at most once, and after its deadline rejects it if the votes ruled approval out, else expires it. A transfer runs through GovernedTransfer in " + tokenChaincode + "; a proposed call can only be finalized by its requester. Emits RequestRejected, RequestExpired or RequestExecuted, which also reports the approval: Fabric keeps one event per transaction",
+				Args:        []contract.Arg{requestIDArg},
+				Returns:     "text \"Request approved\", \"Request denied\" or \"Request expired\"",
+				Handler:     t.finalizeRequest,
+			},
+			{
+				Name:        "cancelRequest",
+				Description: "Withdraws a Pending request. Only its requester may cancel it. Emits RequestCancelled",
+				Args:        []contract.Arg{requestIDArg},
+				Returns:     "nothing",
+				Handler:     t.cancelRequest,
+			},
+			{
+				Name:        "GetRequest",
+				Description: "Returns a request with its votes, state and timestamps",
+				Args:        []contract.Arg{requestIDArg},
+				Returns:     "JSON RequestLine",
+				ReadOnly:    true,
+				Handler:     t.GetRequest,
+			},
+			{
+				Name:        "ListRequests",
+				Description: "Lists requests by status, requester and target, in ID order. Empty filters match every request",
+				Args: []contract.Arg{
+					{Name: "status", Type: "string", Optional: true, Enum: []string{StatusPending, StatusApproved, StatusRejected, StatusExecuted, StatusCancelled, StatusExpired}},
+					{Name: "requester", Type: "string", Optional: true, Format: "account"},
+					{Name: "target", Type: "string", Optional: true, Description: "Target account or alias of transfer requests, or the chaincode of call requests"},
+					pageSizeArg,
+					bookmarkArg,
+				},
+				Returns:  "JSON Lines page of RequestLine",
+				ReadOnly: true,
+				Handler:  t.ListRequests,
+			},
+			{
+				Name:        "PendingForMe",
+				Description: "Lists the Pending requests the caller is a signer of and has not voted on yet, in ID order",
+				Args:        []contract.Arg{pageSizeArg, bookmarkArg},
+				Returns:     "JSON Lines page of RequestLine",
+				ReadOnly:    true,
+				Handler:     t.PendingForMe,
+			},
+			{
+				Name:        "SetSignerGroup",
+				Description: "Creates or replaces a named signer group. Open requests keep the members and weights they were submitted with",
+				Args: []contract.Arg{
+					signerGroupArg,
+					{Name: "members", Type: "string", Format: "json", Description: "JSON array of at least 2 account IDs"},
+					{Name: "weights", Type: "string", Format: "json", Optional: true, Description: `{"members":{"<account>":W},"msps":{"OrgManagerMSP":3}} weighs a member's vote by account, else by MSP, else 1`},
+				},
+				Returns: "nothing",
+				Rule:    &access.Admin,
+				Handler: t.SetSignerGroup,
+			},
+			{
+				Name:     "GetSignerGroup",
+				Args:     []contract.Arg{signerGroupArg},
+				Returns:  "JSON SignerGroup",
+				ReadOnly: true,
+				Handler:  t.GetSignerGroup,
+			},
+			{
+				Name:        "SetDefaultThreshold",
+				Description: "Sets the threshold of requests submitted without one",
+				Args:        []contract.Arg{thresholdArg(false, "")},
+				Returns:     "nothing",
+				Rule:        &managerRule,
+				Handler:     t.SetDefaultThreshold,
+			},
+			{
+				Name:     "GetDefaultThreshold",
+				Returns:  "JSON Threshold, or null for 2/3 of the signers' weight",
+				ReadOnly: true,
+				Handler:  t.GetDefaultThreshold,
+			},
+			{
+				Name:        "ExportRequests",
+				Description: "Exports every request with its votes",
+				Args:        []contract.Arg{pageSizeArg, bookmarkArg},
+				Returns:     "JSON Lines page",
+				ReadOnly:    true,
+				Rule:        &access.Audit,
+				Handler:     t.ExportRequests,
+			},
+		}, migrate.Functions(collections)...),
+	}
+}
@@ -0,0 +1,72 @@
+package multisign
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// Events of a request. Votes emit VoteCast; the request's state changes emit
+// Request<Status> from finalizeRequest and cancelRequest. A request is approved
+// and executed by the same finalizeRequest, which emits only RequestExecuted
+const (
+	SubmittedEvent = "RequestSubmitted"
+	VoteCastEvent  = "VoteCast"
+	ExecutedEvent  = "Request" + StatusExecuted
+)
+
+// RequestEvent is the payload of every request event: RequestSubmitted,
+// VoteCast and Request<Status> on each state change, e.g. RequestRejected.
+// Voter, Vote and Comment are set by votes, Signers by RequestSubmitted, and
+// Decision and Result by RequestExecuted
+type RequestEvent struct {
+	RequestID string      `json:"requestId"`
+	Type      string      `json:"type"`
+	Requester string      `json:"requester"`
+	Status    string      `json:"status"`
+	Previous  string      `json:"previous,omitempty"`
+	Voter     string      `json:"voter,omitempty"`
+	Vote      string      `json:"vote,omitempty"`
+	Comment   string      `json:"comment,omitempty"`
+	Tally     Tally       `json:"tally"`
+	Threshold Threshold   `json:"threshold"`
+	Signers   []string    `json:"signers,omitempty"`
+	Decision  string      `json:"decision,omitempty"`
+	Result    *CallResult `json:"result,omitempty"`
+	TxID      string      `json:"txId"`
+	Timestamp int64       `json:"timestamp"`
+}
+
+// event describes the request as it stands, with the vote of voter if any
+func (r *Request) event(stub shim.ChaincodeStubInterface, requestID string, voter string) RequestEvent {
+	event := RequestEvent{
+		RequestID: requestID,
+		Type:      r.Type,
+		Requester: r.Requester,
+		Status:    r.Status,
+		Tally:     r.tally(),
+		Threshold: r.Threshold,
+		TxID:      stub.GetTxID(),
+		Timestamp: r.UpdatedAt,
+	}
+	if vote, voted := r.Responses[voter]; voted {
+		event.Voter = voter
+		event.Vote = vote.Response
+		event.Comment = vote.Comment
+	}
+	return event
+}
+
+// setRequestEvent sets the transaction's event. A later call replaces it
+func setRequestEvent(stub shim.ChaincodeStubInterface, name string, event RequestEvent) error {
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal event: %s", err)
+	}
+	err = stub.SetEvent(name, eventJSON)
+	if err != nil {
+		return fmt.Errorf("Failed to set event: %s", err)
+	}
+	return nil
+}
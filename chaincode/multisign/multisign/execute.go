@@ -0,0 +1,131 @@
+package multisign
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/chaincode/lib/access"
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// tokenChaincode executes approved transfer requests. It must be installed on
+// the same channel and have this chaincode registered with RegisterGovernor,
+// and the source account must have approved it with ApproveGovernor for the
+// signer group and yes weight the request is decided by
+const tokenChaincode = "token_erc20"
+
+// Request types
+const (
+	// TransferRequest pays Amount from SourceAccount to TargetAccount
+	TransferRequest = "transfer"
+	// CallRequest runs Call, a function of any chaincode
+	CallRequest = "call"
+)
+
+// Call is a chaincode function an approved proposal runs. It runs through the
+// target's access.GovernedFunction with the request's approval, so target
+// functions must admit the request's signer group and yes weight in their
+// access rule, see access.OrGovernedBy. Calls to another channel can only read:
+// Fabric discards their writes
+type Call struct {
+	Chaincode string   `json:"chaincode"`
+	Channel   string   `json:"channel,omitempty"`
+	Function  string   `json:"function"`
+	Args      []string `json:"args"`
+}
+
+// CallResult is the response of an executed request's call
+type CallResult struct {
+	Status  int32  `json:"status"`
+	Payload string `json:"payload"`
+}
+
+// call returns the chaincode call that executes the request
+func (r *Request) call(requestID string) *Call {
+	if r.Type == CallRequest {
+		return r.Call
+	}
+	return &Call{
+		Chaincode: tokenChaincode,
+		Function:  "GovernedTransfer",
+		Args:      []string{r.SourceAccount, r.TargetAccount, strconv.FormatUint(r.Amount, 10), requestID},
+	}
+}
+
+// approval is what this chaincode vouches for when it runs the request's call:
+// the signer group that decided it and the weight that voted yes
+func (r *Request) approval(requestID string) (string, error) {
+	approvalJSON, err := json.Marshal(access.Approval{RequestID: requestID, SignerGroup: r.SignerGroup, YesWeight: r.tally().YesWeight})
+	if err != nil {
+		return "", fmt.Errorf("Failed to marshal approval: %s", err)
+	}
+	return string(approvalJSON), nil
+}
+
+// invoke runs the call through the target's access.GovernedFunction. Calls to
+// this chaincode run through its own catalogue, since a chaincode cannot call
+// itself through InvokeChaincode
+func (t *MultisignChaincode) invoke(stub shim.ChaincodeStubInterface, call *Call, approval string) (pb.Response, error) {
+	governed := append([]string{approval, call.Function}, call.Args...)
+	self, _, err := access.TopLevelCall(stub)
+	if err != nil {
+		return pb.Response{}, err
+	}
+	if call.Chaincode == self && (call.Channel == "" || call.Channel == stub.GetChannelID()) {
+		return t.catalogue().Call(stub, access.GovernedFunction, governed), nil
+	}
+
+	args := [][]byte{[]byte(access.GovernedFunction)}
+	for _, arg := range governed {
+		args = append(args, []byte(arg))
+	}
+	return stub.InvokeChaincode(call.Chaincode, args, call.Channel), nil
+}
+
+// execute runs the approved request's call and moves it to Executed with the
+// call's response and this transaction's ID. A failed call fails the whole
+// transaction, so nothing it wrote is kept and the request can be finalized again.
+// Fabric keeps one event per transaction, so RequestExecuted stands for the
+// approval too: it carries the decision, the result and previous, the state
+// the request was in before this transaction
+func (t *MultisignChaincode) execute(stub shim.ChaincodeStubInterface, requestID string, request *Request, previous string) error {
+	// Transfer requests submitted before amounts existed say nothing about what to pay
+	if request.Type == TransferRequest && (request.Amount == 0 || request.SourceAccount == "") {
+		return errcode.New(errcode.ConflictCode, "Request has no amount or source account. Submit it again")
+	}
+
+	approval, err := request.approval(requestID)
+	if err != nil {
+		return err
+	}
+	call := request.call(requestID)
+	response, err := t.invoke(stub, call, approval)
+	if err != nil {
+		return err
+	}
+	if response.Status >= shim.ERRORTHRESHOLD {
+		failure := errcode.Parse(response)
+		failure.Message = fmt.Sprintf("%s in %s failed: %s", call.Function, call.Chaincode, failure.Message)
+		return failure
+	}
+
+	err = request.advance(stub, StatusExecuted)
+	if err != nil {
+		return err
+	}
+	request.ExecutedTxID = stub.GetTxID()
+	request.Result = &CallResult{Status: response.Status, Payload: string(response.Payload)}
+	err = putRequest(stub, requestID, request)
+	if err != nil {
+		return err
+	}
+
+	event := request.event(stub, requestID, "")
+	event.Previous = previous
+	event.Decision = StatusApproved
+	event.Result = request.Result
+	return setRequestEvent(stub, ExecutedEvent, event)
+}
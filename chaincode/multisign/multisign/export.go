@@ -0,0 +1,59 @@
+package multisign
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/chaincode/lib/export"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// RequestLine is one exported request together with every vote cast on it
+type RequestLine struct {
+	ID string `json:"id"`
+	Request
+}
+
+// requestLine converts a stored request into its RequestLine
+func requestLine(stub shim.ChaincodeStubInterface) func(key string, value []byte) (interface{}, error) {
+	return func(key string, value []byte) (interface{}, error) {
+		_, attributes, err := stub.SplitCompositeKey(key)
+		if err != nil || len(attributes) != 1 {
+			return nil, fmt.Errorf("Invalid request key %q", key)
+		}
+		line := RequestLine{ID: attributes[0]}
+		err = json.Unmarshal(value, &line.Request)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal request %s: %s", attributes[0], err)
+		}
+		return &line, nil
+	}
+}
+
+// ExportRequests returns every request with its votes as JSON Lines, in ID order
+func (t *MultisignChaincode) ExportRequests(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) > 2 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 0 to 2: pageSize, bookmark")
+	}
+	page, err := export.PageArgs(args)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(requestPrefix, []string{})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to query requests: %s", err))
+	}
+	err = page.Iterate(resultsIterator, requestLine(stub))
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	pageBytes, err := page.Bytes(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	return shim.Success(pageBytes)
+}
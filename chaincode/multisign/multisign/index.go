@@ -0,0 +1,167 @@
+package multisign
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// Composite key object types of the request indexes. Each entry maps an
+// attribute value to the ID of a request that has it, so queries read only the
+// requests they return
+const (
+	statusIndex    = "status~id"
+	requesterIndex = "requester~id"
+	targetIndex    = "target~id"
+	signerIndex    = "signer~id"
+)
+
+// indexEntry is the value of every index key; the key itself holds the data
+var indexEntry = []byte{0x00}
+
+// target returns what the request acts on: the chaincode a call request runs,
+// or the account a transfer request pays
+func (r *Request) target() string {
+	if r.Call != nil {
+		return r.Call.Chaincode
+	}
+	return r.TargetAccount
+}
+
+// indexKeys returns the index keys of a request
+func (r *Request) indexKeys(stub shim.ChaincodeStubInterface, requestID string) (map[string]bool, error) {
+	entries := [][]string{{statusIndex, r.Status}, {requesterIndex, r.Requester}}
+	if target := r.target(); target != "" {
+		entries = append(entries, []string{targetIndex, target})
+	}
+	for _, signer := range r.Signers {
+		entries = append(entries, []string{signerIndex, signer})
+	}
+
+	keys := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		key, err := stub.CreateCompositeKey(entry[0], []string{entry[1], requestID})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create index key: %s", err)
+		}
+		keys[key] = true
+	}
+	return keys, nil
+}
+
+// indexRequest moves the index entries of a request from its previous state,
+// nil for a new request, to its current one
+func indexRequest(stub shim.ChaincodeStubInterface, requestID string, previous *Request, request *Request) error {
+	keys, err := request.indexKeys(stub, requestID)
+	if err != nil {
+		return err
+	}
+	if previous != nil {
+		previousKeys, err := previous.indexKeys(stub, requestID)
+		if err != nil {
+			return err
+		}
+		for key := range previousKeys {
+			if keys[key] {
+				delete(keys, key)
+				continue
+			}
+			err = stub.DelState(key)
+			if err != nil {
+				return fmt.Errorf("Failed to delete index entry: %s", err)
+			}
+		}
+	}
+	for key := range keys {
+		err = stub.PutState(key, indexEntry)
+		if err != nil {
+			return fmt.Errorf("Failed to put index entry: %s", err)
+		}
+	}
+	return nil
+}
+
+// storedRequest decodes a request stored at key and returns its ID
+func storedRequest(stub shim.ChaincodeStubInterface, key string, value []byte) (string, *Request, error) {
+	_, attributes, err := stub.SplitCompositeKey(key)
+	if err != nil || len(attributes) != 1 {
+		return "", nil, fmt.Errorf("Invalid request key %q", key)
+	}
+	var request Request
+	err = json.Unmarshal(value, &request)
+	if err != nil {
+		return "", nil, fmt.Errorf("Error unmarshalling request JSON: %s", err)
+	}
+	return attributes[0], &request, nil
+}
+
+// aliasTarget returns the account a transfer request's target alias is
+// registered to, or "" if the target is an account ID or an unknown alias.
+// Requests submitted before targets were resolved hold the alias they were
+// submitted with
+func (r *Request) aliasTarget(stub shim.ChaincodeStubInterface) (string, error) {
+	if r.Call != nil || r.TargetAccount == "" || isAccountID(r.TargetAccount) {
+		return "", nil
+	}
+	return resolveAlias(stub, strings.ToLower(r.TargetAccount))
+}
+
+// reindexRequest writes the index entries of a stored request. Migrate calls it
+// for requests that are missing some, such as those stored before the indexes
+// existed, and for requests whose target is still an alias, which it replaces
+// with the account
+func reindexRequest(stub shim.ChaincodeStubInterface, key string, value []byte) error {
+	requestID, request, err := storedRequest(stub, key, value)
+	if err != nil {
+		return err
+	}
+	err = indexRequest(stub, requestID, nil, request)
+	if err != nil {
+		return err
+	}
+	account, err := request.aliasTarget(stub)
+	if err != nil || account == "" {
+		return err
+	}
+	request.TargetAccount = account
+	return putRequest(stub, requestID, request)
+}
+
+// requestIndexed reports whether every index entry of a stored request exists
+// and lists it under its target account, so Migrate only reindexes requests
+// that need it
+func requestIndexed(stub shim.ChaincodeStubInterface, key string, value []byte) (bool, error) {
+	requestID, request, err := storedRequest(stub, key, value)
+	if err != nil {
+		return false, err
+	}
+	keys, err := request.indexKeys(stub, requestID)
+	if err != nil {
+		return false, err
+	}
+	for key := range keys {
+		entry, err := stub.GetState(key)
+		if err != nil {
+			return false, fmt.Errorf("Failed to get index entry: %s", err)
+		}
+		if entry == nil {
+			return false, nil
+		}
+	}
+	account, err := request.aliasTarget(stub)
+	if err != nil {
+		return false, err
+	}
+	return account == "", nil
+}
+
+// indexedRequestID returns the request ID of an index key
+func indexedRequestID(stub shim.ChaincodeStubInterface, key string) (string, error) {
+	_, attributes, err := stub.SplitCompositeKey(key)
+	if err != nil || len(attributes) != 2 {
+		return "", fmt.Errorf("Invalid index key %q", key)
+	}
+	return attributes[1], nil
+}
@@ -0,0 +1,215 @@
+package multisign
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Request states. A request is Pending until its votes pass the threshold
+// (Approved) or can no longer pass it (Rejected), the requester cancels it or
+// its deadline passes. Finalizing an Approved request executes it
+const (
+	StatusPending   = "Pending"
+	StatusApproved  = "Approved"
+	StatusRejected  = "Rejected"
+	StatusExecuted  = "Executed"
+	StatusCancelled = "Cancelled"
+	StatusExpired   = "Expired"
+)
+
+// transitions lists the states each state may move to
+var transitions = map[string][]string{
+	StatusPending:  {StatusApproved, StatusRejected, StatusCancelled, StatusExpired},
+	StatusApproved: {StatusExecuted},
+}
+
+// defaultVotingPeriod is how long a request stays open for votes when the
+// requester gives no voting period (7 days)
+const defaultVotingPeriod = 7 * 24 * 60 * 60
+
+// txTime returns the transaction timestamp in Unix seconds
+func txTime(stub shim.ChaincodeStubInterface) (int64, error) {
+	ts, err := stub.GetTxTimestamp()
+	if err != nil {
+		return 0, fmt.Errorf("Failed to get transaction timestamp: %s", err)
+	}
+	return ts.GetSeconds(), nil
+}
+
+// parseVotingPeriod returns the deadline of a request submitted at now
+func parseVotingPeriod(votingPeriod string, now int64) (int64, error) {
+	if votingPeriod == "" {
+		return now + defaultVotingPeriod, nil
+	}
+	period, err := strconv.ParseInt(votingPeriod, 10, 64)
+	if err != nil || period <= 0 {
+		return 0, errcode.New(errcode.InvalidArgumentCode, "Voting period must be a positive number of seconds")
+	}
+	return now + period, nil
+}
+
+// expired reports whether the request's voting deadline has passed. Requests
+// from before deadlines existed have none
+func (r *Request) expired(now int64) bool {
+	return r.Deadline > 0 && now >= r.Deadline
+}
+
+// decide returns the state the votes put a pending request in at now. Signers
+// may change their vote until the deadline, so a vote never closes a request:
+// it is Approved once the votes pass the threshold and finalizeRequest settles
+// it, and stays Pending otherwise. Once the deadline has passed it is Rejected
+// if the votes cast ruled approval out, even had every signer who did not vote
+// voted yes, and Expired if too few signers voted. Requests from before
+// deadlines existed stay Pending until approved or cancelled
+func (r *Request) decide(now int64) string {
+	tally := r.tally()
+	if r.Threshold.approved(tally) {
+		return StatusApproved
+	}
+	if !r.expired(now) {
+		return StatusPending
+	}
+	remaining := tally.TotalWeight - tally.VotedWeight
+	tally.YesWeight += remaining
+	tally.VotedWeight += remaining
+	if !r.Threshold.approved(tally) {
+		return StatusRejected
+	}
+	return StatusExpired
+}
+
+// transition moves the request to a new state and emits the matching
+// Request<Status> event. The caller saves the request
+func (r *Request) transition(stub shim.ChaincodeStubInterface, requestID string, status string) error {
+	previous := r.Status
+	err := r.advance(stub, status)
+	if err != nil {
+		return err
+	}
+	event := r.event(stub, requestID, "")
+	event.Previous = previous
+	return setRequestEvent(stub, "Request"+status, event)
+}
+
+// advance moves the request to a new state without emitting an event, for
+// callers that emit one event for several steps. The caller saves the request
+func (r *Request) advance(stub shim.ChaincodeStubInterface, status string) error {
+	legal := false
+	for _, next := range transitions[r.Status] {
+		if next == status {
+			legal = true
+		}
+	}
+	if !legal {
+		return errcode.Newf(errcode.ConflictCode, "Request is %s and cannot become %s", r.Status, status).With("status", r.Status)
+	}
+
+	now, err := txTime(stub)
+	if err != nil {
+		return err
+	}
+	r.Status = status
+	r.UpdatedAt = now
+	return nil
+}
+
+// requestPrefix is the composite key object type of requests, which keeps
+// caller-chosen request IDs apart from every other key on the channel
+const requestPrefix = "request"
+
+func requestKey(stub shim.ChaincodeStubInterface, requestID string) (string, error) {
+	key, err := stub.CreateCompositeKey(requestPrefix, []string{requestID})
+	if err != nil {
+		return "", errcode.Newf(errcode.InvalidArgumentCode, "Invalid request ID: %s", err)
+	}
+	return key, nil
+}
+
+// getRequest loads a request
+func getRequest(stub shim.ChaincodeStubInterface, requestID string) (*Request, error) {
+	key, err := requestKey(stub, requestID)
+	if err != nil {
+		return nil, err
+	}
+	requestJSON, err := stub.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get request from state: %s", err)
+	} else if requestJSON == nil {
+		return nil, errcode.New(errcode.NotFoundCode, "Request does not exist")
+	}
+	var request Request
+	err = json.Unmarshal(requestJSON, &request)
+	if err != nil {
+		return nil, fmt.Errorf("Error unmarshalling request JSON: %s", err)
+	}
+	return &request, nil
+}
+
+// putRequest saves a request and keeps its index entries current
+func putRequest(stub shim.ChaincodeStubInterface, requestID string, request *Request) error {
+	key, err := requestKey(stub, requestID)
+	if err != nil {
+		return err
+	}
+	var previous *Request
+	previousJSON, err := stub.GetState(key)
+	if err != nil {
+		return fmt.Errorf("Failed to get request from state: %s", err)
+	} else if previousJSON != nil {
+		previous = &Request{}
+		err = json.Unmarshal(previousJSON, previous)
+		if err != nil {
+			return fmt.Errorf("Error unmarshalling request JSON: %s", err)
+		}
+	}
+	err = indexRequest(stub, requestID, previous, request)
+	if err != nil {
+		return err
+	}
+
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("Error marshalling request JSON: %s", err)
+	}
+	err = stub.PutState(key, requestJSON)
+	if err != nil {
+		return fmt.Errorf("Error saving request to state: %s", err)
+	}
+	return nil
+}
+
+// cancelRequest withdraws a pending request. Only its requester may cancel it
+func (t *MultisignChaincode) cancelRequest(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1")
+	}
+	requestID := args[0]
+	request, err := getRequest(stub, requestID)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	caller, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal("Failed to get creator")
+	}
+	if hex.EncodeToString(caller) != request.Requester {
+		return errcode.Forbidden("Only the requester can cancel a request")
+	}
+
+	err = request.transition(stub, requestID, StatusCancelled)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	err = putRequest(stub, requestID, request)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	return shim.Success(nil)
+}
@@ -0,0 +1,192 @@
+package multisign
+
+import "testing"
+
+func TestParseVotingPeriod(t *testing.T) {
+	const now = 1700000000
+	tests := []struct {
+		period  string
+		want    int64
+		wantErr bool
+	}{
+		{period: "", want: now + defaultVotingPeriod},
+		{period: "60", want: now + 60},
+		{period: "0", wantErr: true},
+		{period: "-60", wantErr: true},
+		{period: "1h", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseVotingPeriod(tt.period, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseVotingPeriod(%q) error = %v, wantErr %v", tt.period, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseVotingPeriod(%q) = %d, want %d", tt.period, got, tt.want)
+		}
+	}
+}
+
+func TestExpired(t *testing.T) {
+	tests := []struct {
+		name     string
+		deadline int64
+		now      int64
+		want     bool
+	}{
+		{name: "before the deadline", deadline: 100, now: 99},
+		{name: "at the deadline", deadline: 100, now: 100, want: true},
+		{name: "after the deadline", deadline: 100, now: 101, want: true},
+		{name: "no deadline", deadline: 0, now: 101},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Request{Deadline: tt.deadline}
+			if got := r.expired(tt.now); got != tt.want {
+				t.Errorf("expired(%d) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	legal := func(from, to string) bool {
+		for _, next := range transitions[from] {
+			if next == to {
+				return true
+			}
+		}
+		return false
+	}
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{StatusPending, StatusApproved, true},
+		{StatusPending, StatusRejected, true},
+		{StatusPending, StatusCancelled, true},
+		{StatusPending, StatusExpired, true},
+		{StatusPending, StatusExecuted, false},
+		{StatusApproved, StatusExecuted, true},
+		{StatusApproved, StatusCancelled, false},
+		{StatusRejected, StatusApproved, false},
+		{StatusExecuted, StatusPending, false},
+		{StatusCancelled, StatusPending, false},
+		{StatusExpired, StatusApproved, false},
+	}
+	for _, tt := range tests {
+		if got := legal(tt.from, tt.to); got != tt.want {
+			t.Errorf("%s -> %s legal = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+// votes builds the responses of a request from voter -> response
+func votes(responses map[string]string) map[string]*Vote {
+	votes := make(map[string]*Vote, len(responses))
+	for voter, response := range responses {
+		votes[voter] = &Vote{Response: response}
+	}
+	return votes
+}
+
+func TestDecide(t *testing.T) {
+	const deadline = 1000
+	signers := []string{"a", "b", "c"}
+	tests := []struct {
+		name      string
+		threshold Threshold
+		weights   map[string]int
+		responses map[string]string
+		now       int64
+		want      string
+	}{
+		{name: "no votes", threshold: Threshold{Required: 2}, now: 10, want: StatusPending},
+		{name: "enough yes", threshold: Threshold{Required: 2}, responses: map[string]string{"a": VoteYes, "b": VoteYes}, now: 10, want: StatusApproved},
+		{name: "still reachable", threshold: Threshold{Required: 2}, responses: map[string]string{"a": VoteYes, "b": VoteNo}, now: 10, want: StatusPending},
+		{name: "ruled out but votes may change", threshold: Threshold{Required: 2}, responses: map[string]string{"a": VoteNo, "b": VoteAbstain}, now: 10, want: StatusPending},
+		{name: "unanimity after one no", threshold: Threshold{Required: 3}, responses: map[string]string{"a": VoteNo}, now: 10, want: StatusPending},
+		{name: "ruled out at the deadline", threshold: Threshold{Required: 2}, responses: map[string]string{"a": VoteNo, "b": VoteAbstain}, now: deadline, want: StatusRejected},
+		{name: "too few votes at the deadline", threshold: Threshold{Required: 2}, responses: map[string]string{"a": VoteYes}, now: deadline, want: StatusExpired},
+		{name: "approved at the deadline", threshold: Threshold{Required: 2}, responses: map[string]string{"a": VoteYes, "c": VoteYes}, now: deadline + 1, want: StatusApproved},
+		{name: "weighted yes", threshold: Threshold{Required: 4}, weights: map[string]int{"a": 4}, responses: map[string]string{"a": VoteYes}, now: 10, want: StatusApproved},
+		{name: "weighted no at the deadline", threshold: Threshold{Required: 4}, weights: map[string]int{"a": 4}, responses: map[string]string{"a": VoteNo}, now: deadline, want: StatusRejected},
+		{name: "percent below quorum", threshold: Threshold{Percent: 50, Quorum: 2}, responses: map[string]string{"a": VoteYes}, now: 10, want: StatusPending},
+		{name: "percent passed", threshold: Threshold{Percent: 50, Quorum: 2}, responses: map[string]string{"a": VoteYes, "b": VoteNo}, now: 10, want: StatusApproved},
+		{name: "percent failed at the deadline", threshold: Threshold{Percent: 60, Quorum: 3}, responses: map[string]string{"a": VoteNo, "b": VoteNo}, now: deadline, want: StatusRejected},
+		{name: "percent below quorum at the deadline", threshold: Threshold{Percent: 60, Quorum: 3}, responses: map[string]string{"a": VoteYes, "b": VoteNo}, now: deadline, want: StatusExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Request{Signers: signers, Weights: tt.weights, Threshold: tt.threshold, Responses: votes(tt.responses), Deadline: deadline}
+			if got := r.decide(tt.now); got != tt.want {
+				t.Errorf("decide(%d) = %s, want %s", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangedVoteApproves(t *testing.T) {
+	r := &Request{Signers: []string{"a", "b", "c"}, Threshold: Threshold{Required: 3}, Responses: map[string]*Vote{}, Deadline: 1000}
+	for _, voter := range []string{"a", "c"} {
+		if err := r.vote(voter, VoteYes, "", "tx", 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.vote("b", VoteNo, "Wrong amount", "tx", 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.decide(3); got != StatusPending {
+		t.Fatalf("decide() after a mistaken no = %s, want %s", got, StatusPending)
+	}
+	if err := r.vote("b", VoteYes, "Amount checked", "tx", 4); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.decide(5); got != StatusApproved {
+		t.Errorf("decide() after the no changed to yes = %s, want %s", got, StatusApproved)
+	}
+	if history := r.Responses["b"].History; len(history) != 1 || history[0].Response != VoteNo {
+		t.Errorf("history = %+v, want the replaced no", history)
+	}
+}
+
+func TestTally(t *testing.T) {
+	r := &Request{
+		Signers:   []string{"a", "b", "c", "d"},
+		Weights:   map[string]int{"a": 3, "c": 2},
+		Responses: votes(map[string]string{"a": VoteYes, "b": VoteNo, "c": VoteAbstain}),
+	}
+	want := Tally{Yes: 1, No: 1, Abstain: 1, Votes: 3, Signers: 4, YesWeight: 3, NoWeight: 1, AbstainWeight: 2, VotedWeight: 6, TotalWeight: 7}
+	if got := r.tally(); got != want {
+		t.Errorf("tally() = %+v, want %+v", got, want)
+	}
+}
+
+func TestVote(t *testing.T) {
+	r := &Request{Responses: map[string]*Vote{}}
+	steps := []struct {
+		response string
+		comment  string
+		wantErr  bool
+		history  int
+	}{
+		{response: VoteYes},
+		{response: VoteYes, wantErr: true},
+		{response: VoteYes, comment: "on reflection", history: 1},
+		{response: VoteNo, history: 2},
+		{response: VoteNo, wantErr: true},
+	}
+	for i, step := range steps {
+		err := r.vote("a", step.response, step.comment, "tx", int64(i))
+		if (err != nil) != step.wantErr {
+			t.Fatalf("step %d: vote() error = %v, wantErr %v", i, err, step.wantErr)
+		}
+		if step.wantErr {
+			continue
+		}
+		vote := r.Responses["a"]
+		if vote.Response != step.response || len(vote.History) != step.history {
+			t.Errorf("step %d: vote is %s with %d replaced, want %s with %d", i, vote.Response, len(vote.History), step.response, step.history)
+		}
+	}
+}
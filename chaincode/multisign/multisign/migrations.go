@@ -0,0 +1,103 @@
+package multisign
+
+import (
+	"github.com/chaincode/lib/migrate"
+)
+
+// Schema versions of requests and signer groups. Requests have changed shape
+// with nearly every multisign feature; each step is one upgrade below
+const (
+	requestVersion     = 8
+	signerGroupVersion = 2
+)
+
+// requestUpgrades moves stored requests to the current request schema
+var requestUpgrades = []migrate.Upgrade{migrate.Stamp, addSigners, addThreshold, addTransfer, addType, addStatus, addVoteRecords, addWeights}
+
+// collections registers the upgrade functions for every stored object type.
+// Requests used to be stored under their plain ID; "legacy request" upgrades
+// them and moves them under their composite key. Requests missing index
+// entries are reindexed
+var collections = []migrate.Collection{
+	{Name: "legacy request", StartKey: "", EndKey: "", Upgrades: requestUpgrades, Rekey: requestKey},
+	{Name: "request", ObjectType: requestPrefix, Upgrades: requestUpgrades, Index: reindexRequest, Indexed: requestIndexed},
+	{Name: "signergroup", ObjectType: signerGroupPrefix, Upgrades: []migrate.Upgrade{migrate.Stamp, addGroupWeights}},
+}
+
+// addSigners gives version 1 requests an empty signer set. Who was eligible to
+// vote on them was never recorded, so they cannot be finalized and have to be
+// submitted again
+func addSigners(obj map[string]interface{}) error {
+	obj["signers"] = []interface{}{}
+	return nil
+}
+
+// addThreshold gives version 2 requests the 2/3 rule they were decided by
+func addThreshold(obj map[string]interface{}) error {
+	signers, _ := obj["signers"].([]interface{})
+	obj["threshold"] = twoThirds(len(signers))
+	return nil
+}
+
+// addTransfer gives version 3 requests an empty amount and source account.
+// They never said how much to pay, so finalizing them cannot execute a transfer
+func addTransfer(obj map[string]interface{}) error {
+	obj["sourceAccount"] = ""
+	obj["amount"] = 0
+	obj["executed"] = false
+	return nil
+}
+
+// addType marks version 4 requests as transfers, the only kind there was
+func addType(obj map[string]interface{}) error {
+	obj["type"] = TransferRequest
+	return nil
+}
+
+// addStatus replaces the executed flag of version 5 requests with a status.
+// They had no deadline, so they stay open until finalized or cancelled
+func addStatus(obj map[string]interface{}) error {
+	status := StatusPending
+	if executed, _ := obj["executed"].(bool); executed {
+		status = StatusExecuted
+	}
+	delete(obj, "executed")
+	obj["status"] = status
+	obj["createdAt"] = 0
+	obj["deadline"] = 0
+	obj["updatedAt"] = 0
+	return nil
+}
+
+// addVoteRecords turns the plain responses of version 6 requests into vote
+// records. When and in which transaction they were cast was never recorded
+func addVoteRecords(obj map[string]interface{}) error {
+	responses, _ := obj["responses"].(map[string]interface{})
+	votes := make(map[string]interface{}, len(responses))
+	for voter, response := range responses {
+		votes[voter] = map[string]interface{}{"response": response, "txId": "", "timestamp": 0}
+	}
+	obj["responses"] = votes
+	return nil
+}
+
+// addWeights gives every signer of version 7 requests a weight of 1, which
+// their thresholds were counted in
+func addWeights(obj map[string]interface{}) error {
+	signers, _ := obj["signers"].([]interface{})
+	weights := make(map[string]interface{}, len(signers))
+	for _, signer := range signers {
+		if account, ok := signer.(string); ok {
+			weights[account] = 1
+		}
+	}
+	obj["weights"] = weights
+	return nil
+}
+
+// addGroupWeights gives version 1 signer groups no weights, so every member
+// keeps a weight of 1
+func addGroupWeights(obj map[string]interface{}) error {
+	obj["weights"] = map[string]interface{}{}
+	return nil
+}
@@ -0,0 +1,323 @@
+package multisign
+
+import (
+	"encoding/hex"
+	"strconv"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+type MultisignChaincode struct {
+}
+
+// Request asks the signers to approve a transfer of Amount from SourceAccount to
+// TargetAccount or, for call requests, a chaincode Call. Only accounts in
+// Signers may vote, each with their weight in Weights, and Threshold decides
+// when enough weight approved. Once approved, finalizing executes the request
+// and stores the Result. Status follows the lifecycle in lifecycle.go; votes
+// close at Deadline
+type Request struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Type          string           `json:"type"`
+	Requester     string           `json:"requester"`
+	TargetAccount string           `json:"targetAccount"`
+	SourceAccount string           `json:"sourceAccount"`
+	Amount        uint64           `json:"amount"`
+	Call          *Call            `json:"call,omitempty"`
+	Message       string           `json:"message"`
+	Signers       []string         `json:"signers"`
+	SignerGroup   string           `json:"signerGroup,omitempty"`
+	Weights       map[string]int   `json:"weights"`
+	Threshold     Threshold        `json:"threshold"`
+	Responses     map[string]*Vote `json:"responses"`
+	Status        string           `json:"status"`
+	CreatedAt     int64            `json:"createdAt"`
+	Deadline      int64            `json:"deadline"`
+	UpdatedAt     int64            `json:"updatedAt"`
+	ExecutedTxID  string           `json:"executedTxId,omitempty"`
+	Result        *CallResult      `json:"result,omitempty"`
+}
+
+func (t *MultisignChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+// Invoke dispatches to the function catalogue in contract.go
+func (t *MultisignChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	return t.catalogue().Invoke(stub)
+}
+
+// Kich ban la 1 nguoi dai dien gui yeu cau toi tat ca ca nguoi con lai xin duoc chuyen coin cho nguoi nay neu moi nguoi cung dong y >=2/3 thi giao dich do dc xac nhan
+
+func (t *MultisignChaincode) submitRequest(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) < 5 || len(args) > 7 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 5 to 7")
+	}
+
+	requestID := args[0] // La dinh danh cho 1 tien trinh gui yeu cau va nhan dung de xac dinh request nao ung voi respone nao.
+	amount, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil || amount == 0 {
+		return errcode.InvalidArgument("Amount must be a positive integer")
+	}
+	sourceAccount := args[3] // tai khoan (quy) chi tra khi yeu cau duoc chap thuan
+
+	requester, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal("Failed to get creator")
+	}
+
+	// Requests store and index the target as an account ID, whatever alias named it
+	targetAccount, err := resolveAccount(stub, args[1]) // thong tin tai khoan ma ban yeu cau duoc nhan tien.
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	if hex.EncodeToString(requester) == targetAccount {
+		return errcode.InvalidArgument("Requester cannot submit a request to their own account")
+	}
+
+	thresholdJSON := ""
+	if len(args) > 5 {
+		thresholdJSON = args[5]
+	}
+	votingPeriod := ""
+	if len(args) > 6 {
+		votingPeriod = args[6]
+	}
+
+	message := "Do you want " + args[1] + " to receive " + strconv.FormatUint(amount, 10) + " tokens from " + sourceAccount + " ?"
+
+	request := Request{
+		SchemaVersion: requestVersion,
+		Type:          TransferRequest,
+		Requester:     hex.EncodeToString(requester),
+		TargetAccount: targetAccount,
+		SourceAccount: sourceAccount,
+		Amount:        amount,
+		Message:       message,
+		Responses:     make(map[string]*Vote),
+	}
+	return submit(stub, requestID, &request, args[4], thresholdJSON, votingPeriod)
+}
+
+// submit gives a new request its signer set, threshold and deadline and stores it
+// as Pending under requestID, or under the transaction ID if requestID is empty.
+// It never replaces an existing request, and returns the request ID
+func submit(stub shim.ChaincodeStubInterface, requestID string, request *Request, signers string, thresholdJSON string, votingPeriod string) pb.Response {
+	if requestID == "" {
+		requestID = stub.GetTxID()
+	}
+	key, err := requestKey(stub, requestID)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	existing, err := stub.GetState(key)
+	if err != nil {
+		return errcode.Internal("Failed to get request from state")
+	} else if existing != nil {
+		return errcode.New(errcode.ConflictCode, "Request already exists").With("requestId", requestID).Response()
+	}
+
+	now, err := txTime(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	request.Status = StatusPending
+	request.CreatedAt = now
+	request.UpdatedAt = now
+	request.Deadline, err = parseVotingPeriod(votingPeriod, now)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	var group *SignerGroup
+	request.Signers, group, err = resolveSigners(stub, signers, request.Requester)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	if group != nil {
+		request.SignerGroup = group.Name
+	}
+	request.Weights, err = group.weigh(request.Signers)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	request.Threshold, err = resolveThreshold(stub, thresholdJSON, request.totalWeight())
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	err = putRequest(stub, requestID, request)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	event := request.event(stub, requestID, "")
+	event.Signers = request.Signers
+	err = setRequestEvent(stub, SubmittedEvent, event)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	return shim.Success([]byte(requestID))
+}
+
+func (t *MultisignChaincode) respondToRequest(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 && len(args) != 3 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 2 or 3")
+	}
+
+	requestID := args[0] // Lấy requestID từ đối số đầu tiên
+	response := args[1]
+	comment := ""
+	if len(args) == 3 {
+		comment = args[2]
+	}
+
+	if response != VoteYes && response != VoteNo && response != VoteAbstain {
+		return errcode.InvalidArgument("Invalid response. Expecting \"yes\", \"no\" or \"abstain\"")
+	}
+
+	request, err := getRequest(stub, requestID) // Lấy request từ ledger bằng requestID
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	responder, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal("Failed to get creator")
+	}
+
+	responderID := hex.EncodeToString(responder) //ma hoa de co the doc dc
+
+	if request.Requester == responderID {
+		return errcode.Forbidden("Requester cannot respond to their own request")
+	}
+	if !request.isSigner(responderID) {
+		return errcode.Forbidden("Caller is not a signer of this request")
+	}
+	if request.Status != StatusPending {
+		return errcode.New(errcode.ConflictCode, "Request is "+request.Status+" and no longer takes votes").With("status", request.Status).Response()
+	}
+	now, err := txTime(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	if request.expired(now) {
+		return errcode.New(errcode.ConflictCode, "Voting on this request closed at its deadline").With("deadline", request.Deadline).Response()
+	}
+
+	err = request.vote(responderID, response, comment, stub.GetTxID(), now)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	// The request stays Pending so the vote can still be changed; finalizeRequest settles it
+	err = setRequestEvent(stub, VoteCastEvent, request.event(stub, requestID, responderID))
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	err = putRequest(stub, requestID, request) // Lưu cập nhật request vào ledger với cùng requestID
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	return shim.Success(nil)
+}
+
+func (t *MultisignChaincode) evaluateRequest(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1")
+	}
+
+	requestID := args[0]
+
+	request, err := getRequest(stub, requestID)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	tally := request.tally()
+	if tally.Votes == 0 {
+		return errcode.Conflict("No responses found")
+	}
+
+	message := "Total responses: " + strconv.Itoa(tally.Votes) + ", Yes responses: " + strconv.Itoa(tally.Yes) + ", Signers: " + strconv.Itoa(tally.Signers) +
+		", Voted weight: " + strconv.Itoa(tally.VotedWeight) + ", Yes weight: " + strconv.Itoa(tally.YesWeight) + ", Total weight: " + strconv.Itoa(tally.TotalWeight)
+
+	return shim.Success([]byte(message))
+}
+
+// finalizeRequest settles the request. A pending request is approved once its
+// votes pass the threshold; otherwise it stays open until its deadline and is
+// then rejected or expires, see decide. An approved request is executed, at
+// most once
+func (t *MultisignChaincode) finalizeRequest(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1")
+	}
+
+	requestID := args[0]
+
+	request, err := getRequest(stub, requestID)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	// Requests submitted before signer sets existed have none and cannot pass
+	if len(request.Signers) == 0 {
+		return errcode.Conflict("Request has no signer set. Submit it again with signers")
+	}
+
+	previous := request.Status
+	if request.Status == StatusPending {
+		now, err := txTime(stub)
+		if err != nil {
+			return errcode.FromError(err)
+		}
+		status := request.decide(now)
+		if status == StatusPending {
+			tally := request.tally()
+			return errcode.New(errcode.ConflictCode, "Request is still pending").With("yes", tally.Yes).With("votes", tally.Votes).With("signers", tally.Signers).With("yesWeight", tally.YesWeight).With("totalWeight", tally.TotalWeight).With("deadline", request.Deadline).Response()
+		}
+		// An approved request is executed below, and its RequestExecuted event
+		// reports the approval
+		if status == StatusApproved {
+			err = request.advance(stub, status)
+		} else {
+			err = request.transition(stub, requestID, status)
+			if err == nil {
+				err = putRequest(stub, requestID, request)
+			}
+		}
+		if err != nil {
+			return errcode.FromError(err)
+		}
+	}
+
+	switch request.Status {
+	case StatusApproved:
+		// A call runs as whoever finalizes it, so only the requester may
+		if request.Type == CallRequest {
+			finalizer, err := stub.GetCreator()
+			if err != nil {
+				return errcode.Internal("Failed to get creator")
+			}
+			if hex.EncodeToString(finalizer) != request.Requester {
+				return errcode.Forbidden("Only the requester can finalize a call request")
+			}
+		}
+		err = t.execute(stub, requestID, request, previous)
+		if err != nil {
+			return errcode.FromError(err)
+		}
+		return shim.Success([]byte("Request approved"))
+	case StatusRejected:
+		return shim.Success([]byte("Request denied"))
+	case StatusExpired:
+		return shim.Success([]byte("Request expired"))
+	case StatusExecuted:
+		return errcode.New(errcode.ConflictCode, "Request has already been executed in transaction "+request.ExecutedTxID).With("txId", request.ExecutedTxID).Response()
+	}
+	return errcode.New(errcode.ConflictCode, "Request is "+request.Status).With("status", request.Status).Response()
+}
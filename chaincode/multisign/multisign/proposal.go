@@ -0,0 +1,66 @@
+package multisign
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/chaincode/lib/access"
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// submitProposal asks the signers to approve a call of any chaincode function,
+// e.g. updatePersonByAdmin in database or Mint in token_erc20. Once approved,
+// the requester finalizes it and the call runs with their identity. The target
+// admits it only if its rule trusts the signer group that approved it with the
+// yes weight it got, see access.OrGovernedBy
+func (t *MultisignChaincode) submitProposal(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) < 5 || len(args) > 8 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 5 to 8: request ID, chaincode, function, args, signers, optional threshold, channel and voting period")
+	}
+
+	requestID := args[0]
+	var callArgs []string
+	err := json.Unmarshal([]byte(args[3]), &callArgs)
+	if err != nil {
+		return errcode.InvalidArgument("Invalid call arguments, expecting a JSON array of strings: " + err.Error())
+	}
+	thresholdJSON := ""
+	if len(args) > 5 {
+		thresholdJSON = args[5]
+	}
+	call := Call{Chaincode: args[1], Function: args[2], Args: callArgs}
+	if call.Function == access.GovernedFunction {
+		return errcode.InvalidArgument("A proposal names the function to call, not " + access.GovernedFunction)
+	}
+	if len(args) > 6 {
+		call.Channel = args[6]
+	}
+	votingPeriod := ""
+	if len(args) > 7 {
+		votingPeriod = args[7]
+	}
+
+	requester, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal("Failed to get creator")
+	}
+
+	target := call.Chaincode
+	if call.Channel != "" {
+		target += " on " + call.Channel
+	}
+	message := "Do you approve calling " + call.Function + "(" + strings.Join(call.Args, ", ") + ") in " + target + " ?"
+
+	request := Request{
+		SchemaVersion: requestVersion,
+		Type:          CallRequest,
+		Requester:     hex.EncodeToString(requester),
+		Call:          &call,
+		Message:       message,
+		Responses:     make(map[string]*Vote),
+	}
+	return submit(stub, requestID, &request, args[4], thresholdJSON, votingPeriod)
+}
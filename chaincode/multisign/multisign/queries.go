@@ -0,0 +1,153 @@
+package multisign
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/chaincode/lib/export"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// pageArgs starts a page from the pageSize and bookmark arguments at args[i:]
+func pageArgs(args []string, i int) (*export.Page, error) {
+	if len(args) <= i {
+		return export.PageArgs(nil)
+	}
+	return export.PageArgs(args[i:])
+}
+
+// pageResponse finishes a page of requests
+func pageResponse(stub shim.ChaincodeStubInterface, page *export.Page) pb.Response {
+	pageBytes, err := page.Bytes(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	return shim.Success(pageBytes)
+}
+
+// indexedRequests adds to page the requests an index lists under value, in ID
+// order, that match keep
+func indexedRequests(stub shim.ChaincodeStubInterface, page *export.Page, index string, value string, keep func(request *Request) bool) error {
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(index, []string{value})
+	if err != nil {
+		return fmt.Errorf("Failed to query %s: %s", index, err)
+	}
+	return page.Iterate(resultsIterator, func(key string, _ []byte) (interface{}, error) {
+		requestID, err := indexedRequestID(stub, key)
+		if err != nil {
+			return nil, err
+		}
+		request, err := getRequest(stub, requestID)
+		if err != nil {
+			return nil, err
+		}
+		if !keep(request) {
+			return nil, nil
+		}
+		return &RequestLine{ID: requestID, Request: *request}, nil
+	})
+}
+
+// ListRequests returns the requests with the given status, requester and target
+// as JSON Lines, in ID order. Empty filters match every request. Accounts may be
+// given as aliases; a target that is neither is the chaincode of call requests
+func (t *MultisignChaincode) ListRequests(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) > 5 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 0 to 5: status, requester, target, pageSize, bookmark")
+	}
+	filters := make([]string, 3)
+	copy(filters, args)
+	status, requester, target := filters[0], filters[1], filters[2]
+	page, err := pageArgs(args, 3)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	if requester != "" {
+		requester, err = resolveAccount(stub, requester)
+		if err != nil {
+			return errcode.FromError(err)
+		}
+	}
+	if target != "" {
+		target, err = resolveTarget(stub, target)
+		if err != nil {
+			return errcode.FromError(err)
+		}
+	}
+
+	keep := func(request *Request) bool {
+		return (status == "" || request.Status == status) &&
+			(requester == "" || request.Requester == requester) &&
+			(target == "" || request.target() == target)
+	}
+	// Walk the index of the most selective filter and check the others
+	switch {
+	case requester != "":
+		err = indexedRequests(stub, page, requesterIndex, requester, keep)
+	case target != "":
+		err = indexedRequests(stub, page, targetIndex, target, keep)
+	case status != "":
+		err = indexedRequests(stub, page, statusIndex, status, keep)
+	default:
+		var resultsIterator shim.StateQueryIteratorInterface
+		resultsIterator, err = stub.GetStateByPartialCompositeKey(requestPrefix, []string{})
+		if err != nil {
+			return errcode.Internal(fmt.Sprintf("Failed to query requests: %s", err))
+		}
+		err = page.Iterate(resultsIterator, requestLine(stub))
+	}
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	return pageResponse(stub, page)
+}
+
+// PendingForMe returns the Pending requests the caller may still vote on as
+// JSON Lines, in ID order
+func (t *MultisignChaincode) PendingForMe(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) > 2 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 0 to 2: pageSize, bookmark")
+	}
+	page, err := export.PageArgs(args)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	caller, err := stub.GetCreator()
+	if err != nil {
+		return errcode.Internal("Failed to get creator")
+	}
+	signer := hex.EncodeToString(caller)
+	now, err := txTime(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	err = indexedRequests(stub, page, signerIndex, signer, func(request *Request) bool {
+		_, voted := request.Responses[signer]
+		return request.Status == StatusPending && !request.expired(now) && !voted
+	})
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	return pageResponse(stub, page)
+}
+
+// GetRequest returns a request with its votes, state and timestamps as a JSON
+// RequestLine
+func (t *MultisignChaincode) GetRequest(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1: request ID")
+	}
+	request, err := getRequest(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	lineJSON, err := json.Marshal(RequestLine{ID: args[0], Request: *request})
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to marshal request: %s", err))
+	}
+	return shim.Success(lineJSON)
+}
@@ -0,0 +1,216 @@
+package multisign
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// signerGroupPrefix is the composite key object type of named signer groups
+const signerGroupPrefix = "signergroup"
+
+// minGroupSize is the fewest members a signer group may have, so a member
+// requesting from their own group still leaves someone else to approve
+const minGroupSize = 2
+
+// SignerGroup is a named set of accounts that requests take as their signers,
+// with the weight of each member's vote. Only admins set signer groups
+type SignerGroup struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	Name          string       `json:"name"`
+	Members       []string     `json:"members"`
+	Weights       GroupWeights `json:"weights"`
+}
+
+func signerGroupKey(stub shim.ChaincodeStubInterface, name string) (string, error) {
+	key, err := stub.CreateCompositeKey(signerGroupPrefix, []string{name})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create key: %s", err)
+	}
+	return key, nil
+}
+
+func getSignerGroup(stub shim.ChaincodeStubInterface, name string) (*SignerGroup, error) {
+	key, err := signerGroupKey(stub, name)
+	if err != nil {
+		return nil, err
+	}
+	groupJSON, err := stub.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get signer group: %s", err)
+	}
+	if groupJSON == nil {
+		return nil, errcode.Newf(errcode.NotFoundCode, "Signer group %s does not exist", name)
+	}
+	var group SignerGroup
+	err = json.Unmarshal(groupJSON, &group)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal signer group: %s", err)
+	}
+	return &group, nil
+}
+
+// parseAccounts decodes a JSON array of hex account IDs, dropping duplicates
+func parseAccounts(membersJSON string) ([]string, error) {
+	var accounts []string
+	err := json.Unmarshal([]byte(membersJSON), &accounts)
+	if err != nil {
+		return nil, errcode.Newf(errcode.InvalidArgumentCode, "Invalid account list, expecting a JSON array of account IDs: %s", err)
+	}
+	seen := make(map[string]bool)
+	members := []string{}
+	for _, account := range accounts {
+		account = strings.ToLower(account)
+		_, err := hex.DecodeString(account)
+		if err != nil || account == "" {
+			return nil, errcode.Newf(errcode.InvalidArgumentCode, "Invalid account ID %q, expecting a hex-encoded identity", account)
+		}
+		if !seen[account] {
+			seen[account] = true
+			members = append(members, account)
+		}
+	}
+	return members, nil
+}
+
+// containingGroup returns the first signer group, by name, that has every
+// account as a member
+func containingGroup(stub shim.ChaincodeStubInterface, accounts []string) (*SignerGroup, error) {
+	iterator, err := stub.GetStateByPartialCompositeKey(signerGroupPrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query signer groups: %s", err)
+	}
+	defer iterator.Close()
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var group SignerGroup
+		err = json.Unmarshal(kv.Value, &group)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal signer group: %s", err)
+		}
+		members := true
+		for _, account := range accounts {
+			if !contains(group.Members, account) {
+				members = false
+				break
+			}
+		}
+		if members {
+			return &group, nil
+		}
+	}
+	return nil, errcode.New(errcode.ForbiddenCode, "Listed signers must all be members of one signer group set with SetSignerGroup")
+}
+
+// resolveSigners returns the signer set of a new request and the group it was
+// taken from. signers either names a signer group or lists accounts. Only
+// admins set groups, so a list must be drawn from one of them: the request is
+// then decided for the first group, by name, holding every listed account, by
+// those members alone. Governed targets admit approvals by the yes weight of a
+// group's members, which a requester cannot raise by leaving members out. The
+// requester never signs their own request, so they are left out of the set
+func resolveSigners(stub shim.ChaincodeStubInterface, signers string, requester string) ([]string, *SignerGroup, error) {
+	var members []string
+	var group *SignerGroup
+	if strings.HasPrefix(strings.TrimSpace(signers), "[") {
+		accounts, err := parseAccounts(signers)
+		if err != nil {
+			return nil, nil, err
+		}
+		group, err = containingGroup(stub, accounts)
+		if err != nil {
+			return nil, nil, err
+		}
+		members = accounts
+	} else {
+		var err error
+		group, err = getSignerGroup(stub, signers)
+		if err != nil {
+			return nil, nil, err
+		}
+		members = group.Members
+	}
+
+	eligible := []string{}
+	for _, member := range members {
+		if member != requester {
+			eligible = append(eligible, member)
+		}
+	}
+	if len(eligible) == 0 {
+		return nil, nil, errcode.New(errcode.InvalidArgumentCode, "The signer set must contain at least one account other than the requester")
+	}
+	return eligible, group, nil
+}
+
+// isSigner reports whether account may vote on the request
+func (r *Request) isSigner(account string) bool {
+	for _, signer := range r.Signers {
+		if signer == account {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSignerGroup creates or replaces a named signer group, optionally with
+// weights. Requests already submitted keep the members and weights the group
+// had at submission
+func (t *MultisignChaincode) SetSignerGroup(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 && len(args) != 3 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 2 or 3: name, members and optional weights")
+	}
+	members, err := parseAccounts(args[1])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	if len(members) < minGroupSize {
+		return errcode.InvalidArgument(fmt.Sprintf("A signer group must have at least %d members", minGroupSize))
+	}
+
+	group := SignerGroup{SchemaVersion: signerGroupVersion, Name: args[0], Members: members}
+	if len(args) == 3 && args[2] != "" {
+		group.Weights, err = parseGroupWeights(args[2], members)
+		if err != nil {
+			return errcode.FromError(err)
+		}
+	}
+	groupJSON, err := json.Marshal(group)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to marshal signer group: %s", err))
+	}
+	key, err := signerGroupKey(stub, group.Name)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	err = stub.PutState(key, groupJSON)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to save signer group: %s", err))
+	}
+
+	return shim.Success(nil)
+}
+
+// GetSignerGroup returns a signer group as JSON
+func (t *MultisignChaincode) GetSignerGroup(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1: name")
+	}
+	group, err := getSignerGroup(stub, args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	groupJSON, err := json.Marshal(group)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to marshal signer group: %s", err))
+	}
+	return shim.Success(groupJSON)
+}
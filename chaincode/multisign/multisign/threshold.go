@@ -0,0 +1,178 @@
+package multisign
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// configPrefix is the composite key object type of channel-wide settings. A
+// composite key keeps them out of the simple key range that holds requests
+const configPrefix = "config"
+
+// Threshold is the rule that approves a request, in vote weight: each signer's
+// vote counts as their weight, 1 unless their signer group weighs them. Either
+// Required is set, and the request needs that much yes weight out of its
+// signers' total, or Percent is set, and at least Percent% of the yes and no
+// weight must be yes once signers weighing at least Quorum have voted.
+// Abstentions count toward the quorum only
+type Threshold struct {
+	Required int `json:"required,omitempty"`
+	Percent  int `json:"percent,omitempty"`
+	Quorum   int `json:"quorum,omitempty"`
+}
+
+// twoThirds is the threshold used when the request and the channel set none:
+// 2/3 of the signers' total weight, rounded up
+func twoThirds(weight int) Threshold {
+	return Threshold{Required: (2*weight + 2) / 3}
+}
+
+// parseThreshold decodes a threshold given as JSON
+func parseThreshold(thresholdJSON string) (*Threshold, error) {
+	var threshold Threshold
+	decoder := json.NewDecoder(bytes.NewReader([]byte(thresholdJSON)))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&threshold)
+	if err != nil {
+		return nil, errcode.Newf(errcode.InvalidArgumentCode, "Invalid threshold, expecting JSON with required, or percent and quorum: %s", err)
+	}
+	return &threshold, nil
+}
+
+// validate checks the threshold on its own and, when weight is positive,
+// against signers of that total weight
+func (th Threshold) validate(weight int) error {
+	switch {
+	case th.Required > 0 && (th.Percent != 0 || th.Quorum != 0):
+		return errcode.New(errcode.InvalidArgumentCode, "A threshold has either required, or percent and quorum")
+	case th.Required > 0:
+		if weight > 0 && th.Required > weight {
+			return errcode.Newf(errcode.InvalidArgumentCode, "Threshold requires a weight of %d but the signers only weigh %d", th.Required, weight)
+		}
+	case th.Percent > 0:
+		if th.Percent > 100 {
+			return errcode.New(errcode.InvalidArgumentCode, "Threshold percent must be between 1 and 100")
+		}
+		if th.Quorum < 1 {
+			return errcode.New(errcode.InvalidArgumentCode, "A percentage threshold needs a quorum of at least 1")
+		}
+		if weight > 0 && th.Quorum > weight {
+			return errcode.Newf(errcode.InvalidArgumentCode, "Threshold quorum is %d but the signers only weigh %d", th.Quorum, weight)
+		}
+	default:
+		return errcode.New(errcode.InvalidArgumentCode, "A threshold needs a positive required count or percent")
+	}
+	return nil
+}
+
+// approved reports whether the votes of tally pass the threshold
+func (th Threshold) approved(tally Tally) bool {
+	if th.Required > 0 {
+		return tally.YesWeight >= th.Required
+	}
+	return tally.VotedWeight >= th.Quorum && tally.YesWeight > 0 && 100*tally.YesWeight >= th.Percent*(tally.YesWeight+tally.NoWeight)
+}
+
+func defaultThresholdKey(stub shim.ChaincodeStubInterface) (string, error) {
+	key, err := stub.CreateCompositeKey(configPrefix, []string{"defaultThreshold"})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create key: %s", err)
+	}
+	return key, nil
+}
+
+// getDefaultThreshold returns the channel's default threshold, or nil if the
+// manager org has not set one
+func getDefaultThreshold(stub shim.ChaincodeStubInterface) (*Threshold, error) {
+	key, err := defaultThresholdKey(stub)
+	if err != nil {
+		return nil, err
+	}
+	thresholdJSON, err := stub.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get default threshold: %s", err)
+	}
+	if thresholdJSON == nil {
+		return nil, nil
+	}
+	var threshold Threshold
+	err = json.Unmarshal(thresholdJSON, &threshold)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal default threshold: %s", err)
+	}
+	return &threshold, nil
+}
+
+// resolveThreshold returns the threshold of a new request whose signers have the
+// given total weight: the one passed with the request if any, else the channel
+// default, else 2/3 of the weight
+func resolveThreshold(stub shim.ChaincodeStubInterface, thresholdJSON string, weight int) (Threshold, error) {
+	var threshold *Threshold
+	var err error
+	if thresholdJSON != "" {
+		threshold, err = parseThreshold(thresholdJSON)
+	} else {
+		threshold, err = getDefaultThreshold(stub)
+	}
+	if err != nil {
+		return Threshold{}, err
+	}
+	if threshold == nil {
+		return twoThirds(weight), nil
+	}
+	err = threshold.validate(weight)
+	if err != nil {
+		return Threshold{}, err
+	}
+	return *threshold, nil
+}
+
+// SetDefaultThreshold sets the threshold of requests submitted without one.
+// Requests already submitted keep their threshold
+func (t *MultisignChaincode) SetDefaultThreshold(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1: threshold")
+	}
+	threshold, err := parseThreshold(args[0])
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	err = threshold.validate(0)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	thresholdJSON, err := json.Marshal(threshold)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to marshal threshold: %s", err))
+	}
+	key, err := defaultThresholdKey(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	err = stub.PutState(key, thresholdJSON)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to save default threshold: %s", err))
+	}
+
+	return shim.Success(nil)
+}
+
+// GetDefaultThreshold returns the channel's default threshold as JSON, or null
+// when requests fall back to 2/3 of their signers' weight
+func (t *MultisignChaincode) GetDefaultThreshold(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	threshold, err := getDefaultThreshold(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	thresholdJSON, err := json.Marshal(threshold)
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to marshal threshold: %s", err))
+	}
+	return shim.Success(thresholdJSON)
+}
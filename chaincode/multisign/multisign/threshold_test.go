@@ -0,0 +1,80 @@
+package multisign
+
+import "testing"
+
+func TestTwoThirds(t *testing.T) {
+	tests := []struct {
+		weight   int
+		required int
+	}{
+		{1, 1},
+		{2, 2},
+		{3, 2},
+		{4, 3},
+		{5, 4},
+		{6, 4},
+		{9, 6},
+		{10, 7},
+	}
+	for _, tt := range tests {
+		if got := twoThirds(tt.weight); got.Required != tt.required {
+			t.Errorf("twoThirds(%d) requires %d, want %d", tt.weight, got.Required, tt.required)
+		}
+	}
+}
+
+func TestThresholdValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold Threshold
+		weight    int
+		wantErr   bool
+	}{
+		{name: "required", threshold: Threshold{Required: 2}, weight: 3},
+		{name: "required of all", threshold: Threshold{Required: 3}, weight: 3},
+		{name: "required above the weight", threshold: Threshold{Required: 4}, weight: 3, wantErr: true},
+		{name: "required without signers", threshold: Threshold{Required: 40}},
+		{name: "percent", threshold: Threshold{Percent: 51, Quorum: 2}, weight: 3},
+		{name: "unanimous", threshold: Threshold{Percent: 100, Quorum: 3}, weight: 3},
+		{name: "percent above 100", threshold: Threshold{Percent: 101, Quorum: 1}, wantErr: true},
+		{name: "percent without quorum", threshold: Threshold{Percent: 50}, wantErr: true},
+		{name: "quorum above the weight", threshold: Threshold{Percent: 50, Quorum: 4}, weight: 3, wantErr: true},
+		{name: "required and percent", threshold: Threshold{Required: 2, Percent: 50}, wantErr: true},
+		{name: "required and quorum", threshold: Threshold{Required: 2, Quorum: 1}, wantErr: true},
+		{name: "empty", threshold: Threshold{}, wantErr: true},
+		{name: "negative", threshold: Threshold{Required: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.threshold.validate(tt.weight)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate(%d) error = %v, wantErr %v", tt.weight, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestThresholdApproved(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold Threshold
+		tally     Tally
+		want      bool
+	}{
+		{name: "required met", threshold: Threshold{Required: 2}, tally: Tally{YesWeight: 2, VotedWeight: 2}, want: true},
+		{name: "required exceeded", threshold: Threshold{Required: 2}, tally: Tally{YesWeight: 5, VotedWeight: 5}, want: true},
+		{name: "required short", threshold: Threshold{Required: 2}, tally: Tally{YesWeight: 1, NoWeight: 3, VotedWeight: 4}},
+		{name: "percent met", threshold: Threshold{Percent: 60, Quorum: 5}, tally: Tally{YesWeight: 3, NoWeight: 2, VotedWeight: 5}, want: true},
+		{name: "percent short", threshold: Threshold{Percent: 61, Quorum: 5}, tally: Tally{YesWeight: 3, NoWeight: 2, VotedWeight: 5}},
+		{name: "below quorum", threshold: Threshold{Percent: 50, Quorum: 3}, tally: Tally{YesWeight: 2, VotedWeight: 2}},
+		{name: "abstentions make the quorum", threshold: Threshold{Percent: 50, Quorum: 3}, tally: Tally{YesWeight: 1, AbstainWeight: 2, VotedWeight: 3}, want: true},
+		{name: "only abstentions", threshold: Threshold{Percent: 50, Quorum: 3}, tally: Tally{AbstainWeight: 3, VotedWeight: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.threshold.approved(tt.tally); got != tt.want {
+				t.Errorf("approved(%+v) = %v, want %v", tt.tally, got, tt.want)
+			}
+		})
+	}
+}
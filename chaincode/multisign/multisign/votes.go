@@ -0,0 +1,73 @@
+package multisign
+
+import (
+	"github.com/chaincode/lib/errcode"
+)
+
+// Vote responses. An abstention counts toward a quorum but not toward approval
+const (
+	VoteYes     = "yes"
+	VoteNo      = "no"
+	VoteAbstain = "abstain"
+)
+
+// Vote is a signer's current vote on a request. History holds the votes it
+// replaced, oldest first
+type Vote struct {
+	Response  string `json:"response"`
+	Comment   string `json:"comment,omitempty"`
+	TxID      string `json:"txId"`
+	Timestamp int64  `json:"timestamp"`
+	History   []Vote `json:"history,omitempty"`
+}
+
+// Tally is the count of votes on a request, by number of signers and by their
+// weight. Votes and VotedWeight include abstentions
+type Tally struct {
+	Yes           int `json:"yes"`
+	No            int `json:"no"`
+	Abstain       int `json:"abstain"`
+	Votes         int `json:"votes"`
+	Signers       int `json:"signers"`
+	YesWeight     int `json:"yesWeight"`
+	NoWeight      int `json:"noWeight"`
+	AbstainWeight int `json:"abstainWeight"`
+	VotedWeight   int `json:"votedWeight"`
+	TotalWeight   int `json:"totalWeight"`
+}
+
+// tally counts the votes cast
+func (r *Request) tally() Tally {
+	tally := Tally{Votes: len(r.Responses), Signers: len(r.Signers), TotalWeight: r.totalWeight()}
+	for voter, vote := range r.Responses {
+		weight := r.weight(voter)
+		tally.VotedWeight += weight
+		switch vote.Response {
+		case VoteYes:
+			tally.Yes++
+			tally.YesWeight += weight
+		case VoteNo:
+			tally.No++
+			tally.NoWeight += weight
+		case VoteAbstain:
+			tally.Abstain++
+			tally.AbstainWeight += weight
+		}
+	}
+	return tally
+}
+
+// vote records the voter's response, or changes their earlier vote and keeps it
+// in the history. Casting the same vote twice is a conflict
+func (r *Request) vote(voter string, response string, comment string, txID string, now int64) error {
+	vote := &Vote{Response: response, Comment: comment, TxID: txID, Timestamp: now}
+	if previous, exists := r.Responses[voter]; exists {
+		if previous.Response == response && previous.Comment == comment {
+			return errcode.New(errcode.ConflictCode, "Responder has already submitted this response").With("response", response)
+		}
+		vote.History = append(previous.History, Vote{Response: previous.Response, Comment: previous.Comment, TxID: previous.TxID, Timestamp: previous.Timestamp})
+	}
+	r.Responses[voter] = vote
+	r.UpdatedAt = now
+	return nil
+}
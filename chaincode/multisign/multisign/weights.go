@@ -0,0 +1,104 @@
+package multisign
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/chaincode/lib/access"
+	"github.com/chaincode/lib/errcode"
+)
+
+// maxWeight bounds the weight of one signer
+const maxWeight = 1000
+
+// GroupWeights gives the members of a signer group more than one vote. A
+// member's weight is their entry in Members, else the entry of their MSP in
+// MSPs, else 1
+type GroupWeights struct {
+	Members map[string]int `json:"members,omitempty"`
+	MSPs    map[string]int `json:"msps,omitempty"`
+}
+
+// parseGroupWeights decodes the weights of a signer group with the given members
+func parseGroupWeights(weightsJSON string, members []string) (GroupWeights, error) {
+	var weights GroupWeights
+	decoder := json.NewDecoder(bytes.NewReader([]byte(weightsJSON)))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&weights)
+	if err != nil {
+		return GroupWeights{}, errcode.Newf(errcode.InvalidArgumentCode, "Invalid weights, expecting JSON with members and msps: %s", err)
+	}
+
+	memberWeights := make(map[string]int, len(weights.Members))
+	for account, weight := range weights.Members {
+		account = strings.ToLower(account)
+		if !contains(members, account) {
+			return GroupWeights{}, errcode.Newf(errcode.InvalidArgumentCode, "Weighted account %s is not a member of the group", account)
+		}
+		memberWeights[account] = weight
+	}
+	weights.Members = memberWeights
+
+	for _, table := range []map[string]int{weights.Members, weights.MSPs} {
+		for name, weight := range table {
+			if weight < 1 || weight > maxWeight {
+				return GroupWeights{}, errcode.Newf(errcode.InvalidArgumentCode, "Weight of %s must be between 1 and %d", name, maxWeight)
+			}
+		}
+	}
+	return weights, nil
+}
+
+// weigh returns the weight of each signer taken from the group. Signers
+// listed without a group all weigh 1
+func (g *SignerGroup) weigh(signers []string) (map[string]int, error) {
+	weights := make(map[string]int, len(signers))
+	for _, signer := range signers {
+		weights[signer] = 1
+		if g == nil {
+			continue
+		}
+		if weight, ok := g.Weights.Members[signer]; ok {
+			weights[signer] = weight
+			continue
+		}
+		if len(g.Weights.MSPs) == 0 {
+			continue
+		}
+		mspID, err := access.AccountMSPID(signer)
+		if err != nil {
+			return nil, errcode.Newf(errcode.InvalidArgumentCode, "Cannot weigh signer %s of group %s: %s", signer, g.Name, err)
+		}
+		if weight, ok := g.Weights.MSPs[mspID]; ok {
+			weights[signer] = weight
+		}
+	}
+	return weights, nil
+}
+
+// weight returns the weight of a signer's vote on the request
+func (r *Request) weight(signer string) int {
+	if weight, ok := r.Weights[signer]; ok && weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// totalWeight returns the combined weight of all signers
+func (r *Request) totalWeight() int {
+	total := 0
+	for _, signer := range r.Signers {
+		total += r.weight(signer)
+	}
+	return total
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package multisign
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/msp"
+)
+
+// account returns the account ID of an identity of the MSP
+func account(t *testing.T, mspID string, cert string) string {
+	t.Helper()
+	creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: mspID, IdBytes: []byte(cert)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(creator)
+}
+
+func TestParseGroupWeights(t *testing.T) {
+	members := []string{"aa", "bb"}
+	tests := []struct {
+		name    string
+		weights string
+		want    map[string]int
+		wantErr bool
+	}{
+		{name: "empty", weights: `{}`, want: map[string]int{}},
+		{name: "members", weights: `{"members":{"aa":3}}`, want: map[string]int{"aa": 3}},
+		{name: "upper case member", weights: `{"members":{"AA":2}}`, want: map[string]int{"aa": 2}},
+		{name: "msps", weights: `{"msps":{"OrgManagerMSP":5}}`, want: map[string]int{}},
+		{name: "largest weight", weights: `{"members":{"bb":1000}}`, want: map[string]int{"bb": 1000}},
+		{name: "not a member", weights: `{"members":{"cc":2}}`, wantErr: true},
+		{name: "zero weight", weights: `{"members":{"aa":0}}`, wantErr: true},
+		{name: "weight too large", weights: `{"members":{"aa":1001}}`, wantErr: true},
+		{name: "negative msp weight", weights: `{"msps":{"OrgStaffMSP":-1}}`, wantErr: true},
+		{name: "unknown field", weights: `{"accounts":{}}`, wantErr: true},
+		{name: "not json", weights: `weights`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseGroupWeights(tt.weights, members)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseGroupWeights() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got.Members) != len(tt.want) {
+				t.Fatalf("parseGroupWeights() members = %v, want %v", got.Members, tt.want)
+			}
+			for member, weight := range tt.want {
+				if got.Members[member] != weight {
+					t.Errorf("weight of %s = %d, want %d", member, got.Members[member], weight)
+				}
+			}
+		})
+	}
+}
+
+func TestWeigh(t *testing.T) {
+	manager := account(t, "OrgManagerMSP", "manager")
+	staff := account(t, "OrgStaffMSP", "staff")
+	chair := account(t, "OrgStaffMSP", "chair")
+	group := &SignerGroup{
+		Name:    "board",
+		Members: []string{manager, staff, chair},
+		Weights: GroupWeights{Members: map[string]int{chair: 4}, MSPs: map[string]int{"OrgManagerMSP": 3}},
+	}
+	tests := []struct {
+		name    string
+		group   *SignerGroup
+		signers []string
+		want    map[string]int
+		wantErr bool
+	}{
+		{name: "no group", signers: []string{manager, chair}, want: map[string]int{manager: 1, chair: 1}},
+		{name: "member weight before msp weight", group: group, signers: []string{manager, staff, chair}, want: map[string]int{manager: 3, staff: 1, chair: 4}},
+		{name: "unweighted group", group: &SignerGroup{Name: "plain"}, signers: []string{"zz"}, want: map[string]int{"zz": 1}},
+		{name: "signer that is no identity", group: group, signers: []string{"zz"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.group.weigh(tt.signers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("weigh() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for signer, weight := range tt.want {
+				if got[signer] != weight {
+					t.Errorf("weight of %s = %d, want %d", signer, got[signer], weight)
+				}
+			}
+		})
+	}
+}
+
+func TestTotalWeight(t *testing.T) {
+	tests := []struct {
+		name    string
+		signers []string
+		weights map[string]int
+		want    int
+	}{
+		{name: "unweighted", signers: []string{"a", "b", "c"}, want: 3},
+		{name: "weighted", signers: []string{"a", "b", "c"}, weights: map[string]int{"a": 5, "b": 2}, want: 8},
+		{name: "weights of non-signers ignored", signers: []string{"a"}, weights: map[string]int{"z": 9}, want: 1},
+		{name: "zero weight counts as 1", signers: []string{"a", "b"}, weights: map[string]int{"a": 0}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Request{Signers: tt.signers, Weights: tt.weights}
+			if got := r.totalWeight(); got != tt.want {
+				t.Errorf("totalWeight() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
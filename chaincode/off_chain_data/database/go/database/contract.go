@@ -0,0 +1,96 @@
+package database
+
+import (
+	"github.com/chaincode/lib/access"
+	"github.com/chaincode/lib/contract"
+	"github.com/chaincode/lib/migrate"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// governedAdmin admits administrators or a multisign proposal the board approved
+var governedAdmin = access.Admin.OrGovernedBy(access.Board)
+
+// catalogue is the function catalogue of the database chaincode. Invoke
+// dispatches through it and GetMetadata publishes it
+func (t *DatabaseChaincode) catalogue() *contract.Contract {
+	idArg := contract.Arg{Name: "id", Type: "string", Pattern: `^[A-Za-z0-9_.@-]+$`, MaxLength: 64, Description: "Employee ID, e.g. Emp1"}
+	nameArg := contract.Arg{Name: "name", Type: "string", MaxLength: 128}
+	ageArg := contract.Arg{Name: "age", Type: "integer"}
+	orgArg := contract.Arg{Name: "org", Type: "string", MaxLength: 64, Description: "staff, accountant or manager"}
+	ethAddressArg := contract.Arg{Name: "ethAddress", Type: "string", Format: "eth-address", Description: "Ethereum address, e.g. 0xf7D8dA6a7a04aCdAe76421F07CF29f38f93F1Ed2"}
+	return &contract.Contract{
+		Name:        "database",
+		Description: "Employee directory mapping employees to their organisation and Ethereum address",
+		Functions: append([]contract.Function{
+			{
+				Name:        "initPerson",
+				Description: "Adds an employee",
+				Args: []contract.Arg{
+					idArg,
+					nameArg,
+					ageArg,
+					orgArg,
+					ethAddressArg,
+				},
+				Returns: "nothing",
+				Handler: t.initPerson,
+			},
+			{
+				Name:     "queryAll",
+				Returns:  "JSON array of {Key, Record}",
+				ReadOnly: true,
+				Handler: func(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+					return t.queryAll(stub)
+				},
+			},
+			{
+				Name:     "queryById",
+				Args:     []contract.Arg{idArg},
+				Returns:  "JSON person, empty if not found",
+				ReadOnly: true,
+				Handler:  t.queryById,
+			},
+			{
+				Name:     "getEthAddress",
+				Args:     []contract.Arg{idArg},
+				Returns:  "Ethereum address",
+				ReadOnly: true,
+				Handler:  t.getEthAddress,
+			},
+			{
+				Name:        "updatePerson",
+				Description: "Updates an employee's age and Ethereum address",
+				Args:        []contract.Arg{idArg, ageArg, ethAddressArg},
+				Returns:     "nothing",
+				Handler:     t.updatePerson,
+			},
+			{
+				Name:        "updatePersonByAdmin",
+				Description: "Updates every field of an employee",
+				Args: []contract.Arg{
+					idArg,
+					nameArg,
+					ageArg,
+					orgArg,
+					ethAddressArg,
+				},
+				Returns: "nothing",
+				Rule:    &governedAdmin,
+				Handler: t.updatePersonByAdmin,
+			},
+			{
+				Name:        "ExportPersons",
+				Description: "Exports every employee record",
+				Args: []contract.Arg{
+					{Name: "pageSize", Type: "integer", Optional: true, Description: "Records per page, 1 to 1000, default 100"},
+					{Name: "bookmark", Type: "string", Optional: true, Description: "Bookmark from the previous page"},
+				},
+				Returns:  "JSON Lines page",
+				ReadOnly: true,
+				Rule:     &access.Audit,
+				Handler:  t.ExportPersons,
+			},
+		}, migrate.Functions(collections)...),
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package database
 
 import (
 	"encoding/json"
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/chaincode/lib/errcode"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	pb "github.com/hyperledger/fabric/protos/peer"
 )
@@ -14,37 +15,22 @@ type DatabaseChaincode struct {
 }
 
 type person struct {
-	ObjectType string `json:"docType"`
-	ID       string `json:"id"`
-	Name     string `json:"name"`
-	Age      int    `json:"age"`
-	Org      string `json:"org"`
-	EthAddress string `json:"ethaddress"`
+	ObjectType    string `json:"docType"`
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Age           int    `json:"age"`
+	Org           string `json:"org"`
+	EthAddress    string `json:"ethaddress"`
+	SchemaVersion int    `json:"schemaVersion"`
 }
 
 func (t *DatabaseChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
 	return shim.Success(nil)
 }
 
-
+// Invoke dispatches to the function catalogue in contract.go
 func (t *DatabaseChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
-    function, args := stub.GetFunctionAndParameters()
-    
-    switch function {
-    case "initPerson":
-    	return t.initPerson(stub, args)
-    case "queryAll":
-    	return t.queryAll(stub)
-    case "queryById":
-    	return t.queryById(stub, args) 
-    case "getEthAddress": 
-	    return t.getEthAddress(stub, args) 
-    case "updatePerson":
-    	return t.updatePerson(stub, args)
-    case "updatePersonByAdmin":
-        return t.updatePersonByAdmin(stub, args)
-    }
-    return shim.Error("Invalid function name")
+	return t.catalogue().Invoke(stub)
 }
 
 func (t *DatabaseChaincode) initPerson(stub shim.ChaincodeStubInterface, args []string) pb.Response {
@@ -53,31 +39,31 @@ func (t *DatabaseChaincode) initPerson(stub shim.ChaincodeStubInterface, args []
 	//   0          1       2      3                        4
 	// "Emp1", "Tuan",   "35",  "Staff/Accountant/Manager"  0xf7D8dA6a7a04aCdAe76421F07CF29f38f93F1Ed2
 	if len(args) != 5 {
-		return shim.Error("Incorrect number of arguments. Expecting 4")
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 5")
 	}
 
 	// ==== Input sanitation ====
 	fmt.Println("- start init person")
 	if len(args[0]) <= 0 {
-		return shim.Error("1st argument must be a non-empty string")
+		return errcode.InvalidArgument("1st argument must be a non-empty string")
 	}
 	if len(args[1]) <= 0 {
-		return shim.Error("2nd argument must be a non-empty string")
+		return errcode.InvalidArgument("2nd argument must be a non-empty string")
 	}
 	if len(args[2]) <= 0 {
-		return shim.Error("3rd argument must be a non-empty string")
+		return errcode.InvalidArgument("3rd argument must be a non-empty string")
 	}
 	if len(args[3]) <= 0 {
-		return shim.Error("4th argument must be a non-empty string")
+		return errcode.InvalidArgument("4th argument must be a non-empty string")
 	}
 	if len(args[4]) <= 0 {
-		return shim.Error("5th argument must be a non-empty string")
+		return errcode.InvalidArgument("5th argument must be a non-empty string")
 	}
-	ID:= args[0]
+	ID := args[0]
 	Name := strings.ToLower(args[1])
 	Age, err := strconv.Atoi(args[2])
 	if err != nil {
-	return shim.Error("1rd argument must be a numeric string")
+		return errcode.InvalidArgument("3rd argument must be a numeric string")
 	}
 	Org := strings.ToLower(args[3])
 	EthAddress := string(args[4])
@@ -85,10 +71,10 @@ func (t *DatabaseChaincode) initPerson(stub shim.ChaincodeStubInterface, args []
 	// ==== Check if marble already exists ====
 	PersonAsBytes, err := stub.GetState(ID)
 	if err != nil {
-		return shim.Error("Failed to get person: " + err.Error())
+		return errcode.Internal("Failed to get person: " + err.Error())
 	} else if PersonAsBytes != nil {
 		fmt.Println("This person already exists: " + ID)
-		return shim.Error("This person already exists: " + ID)
+		return errcode.Conflict("This person already exists: " + ID)
 	}
 
 	// ==== Set objectType based on Org ====
@@ -103,18 +89,18 @@ func (t *DatabaseChaincode) initPerson(stub shim.ChaincodeStubInterface, args []
 	default:
 		objectType = "Employee"
 	}
-	
-	person := &person{objectType, ID, Name, Age, Org, EthAddress}
+
+	person := &person{objectType, ID, Name, Age, Org, EthAddress, personVersion}
 	personJSONasBytes, err := json.Marshal(person)
 	if err != nil {
-		return shim.Error(err.Error())
+		return errcode.FromError(err)
 	}
-	
+
 	err = stub.PutState(ID, personJSONasBytes)
 	if err != nil {
-		return shim.Error(err.Error())
+		return errcode.FromError(err)
 	}
-	
+
 	// ==== Marble saved and indexed. Return success ====
 	fmt.Println("- end init person")
 	return shim.Success(nil)
@@ -127,7 +113,7 @@ func (t *DatabaseChaincode) queryAll(stub shim.ChaincodeStubInterface) pb.Respon
 	// Get all the keys from startKey to endKey
 	resultsIterator, err := stub.GetStateByRange(startKey, endKey)
 	if err != nil {
-		return shim.Error(err.Error())
+		return errcode.FromError(err)
 	}
 	defer resultsIterator.Close()
 
@@ -142,7 +128,7 @@ func (t *DatabaseChaincode) queryAll(stub shim.ChaincodeStubInterface) pb.Respon
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
-			return shim.Error(err.Error())
+			return errcode.FromError(err)
 		}
 
 		// Add a comma before array members, suppress it for the first array member
@@ -173,49 +159,52 @@ func (t *DatabaseChaincode) queryAll(stub shim.ChaincodeStubInterface) pb.Respon
 	return shim.Success([]byte(buffer.String()))
 }
 
-//queryById
+// queryById
 func (t *DatabaseChaincode) queryById(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 
 	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1")
 	}
 
-	personAsBytes, _ := stub.GetState(args[0])
+	personAsBytes, err := stub.GetState(args[0])
+	if err != nil {
+		return errcode.Internal("Failed to get person: " + err.Error())
+	}
 	return shim.Success(personAsBytes)
 }
 
 func (t *DatabaseChaincode) getEthAddress(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 1")
 	}
 
 	personAsBytes, err := stub.GetState(args[0])
 	if err != nil {
-		return shim.Error("Failed to get person: " + err.Error())
+		return errcode.Internal("Failed to get person: " + err.Error())
 	} else if personAsBytes == nil {
-		return shim.Error("Person not found")
+		return errcode.NotFound("Person not found")
 	}
 
 	var person person
 	err = json.Unmarshal(personAsBytes, &person)
 	if err != nil {
-		return shim.Error("Failed to unmarshal person: " + err.Error())
+		return errcode.Internal("Failed to unmarshal person: " + err.Error())
 	}
 
 	return shim.Success([]byte(person.EthAddress))
 }
 
-//ham nay danh rieng cho Admin
+// ham nay danh rieng cho Admin
 func (t *DatabaseChaincode) updatePersonByAdmin(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	if len(args) != 5 {
-		return shim.Error("Incorrect number of arguments. Expecting 5")
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 5")
 	}
 
 	ID := args[0]
 	Name := strings.ToLower(args[1])
 	Age, err := strconv.Atoi(args[2])
 	if err != nil {
-		return shim.Error("3rd argument must be a numeric string")
+		return errcode.InvalidArgument("3rd argument must be a numeric string")
 	}
 	Org := strings.ToLower(args[3])
 	EthAddress := string(args[4])
@@ -223,15 +212,15 @@ func (t *DatabaseChaincode) updatePersonByAdmin(stub shim.ChaincodeStubInterface
 	// Get the existing person
 	personAsBytes, err := stub.GetState(ID)
 	if err != nil {
-		return shim.Error("Failed to get person: " + err.Error())
+		return errcode.Internal("Failed to get person: " + err.Error())
 	} else if personAsBytes == nil {
-		return shim.Error("Person not found")
+		return errcode.NotFound("Person not found")
 	}
 
 	var person person
 	err = json.Unmarshal(personAsBytes, &person)
 	if err != nil {
-		return shim.Error("Failed to unmarshal person: " + err.Error())
+		return errcode.Internal("Failed to unmarshal person: " + err.Error())
 	}
 
 	// Update the person details
@@ -243,13 +232,13 @@ func (t *DatabaseChaincode) updatePersonByAdmin(stub shim.ChaincodeStubInterface
 	// Marshal the updated person object to JSON
 	personJSONasBytes, err := json.Marshal(person)
 	if err != nil {
-		return shim.Error(err.Error())
+		return errcode.FromError(err)
 	}
 
 	// Save the updated person back to the ledger
 	err = stub.PutState(ID, personJSONasBytes)
 	if err != nil {
-		return shim.Error(err.Error())
+		return errcode.FromError(err)
 	}
 
 	return shim.Success(nil)
@@ -257,28 +246,28 @@ func (t *DatabaseChaincode) updatePersonByAdmin(stub shim.ChaincodeStubInterface
 
 func (t *DatabaseChaincode) updatePerson(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	if len(args) != 3 {
-		return shim.Error("Incorrect number of arguments. Expecting 3")
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 3")
 	}
 
 	ID := args[0]
 	Age, err := strconv.Atoi(args[1])
 	if err != nil {
-		return shim.Error("2nd argument must be a numeric string")
+		return errcode.InvalidArgument("2nd argument must be a numeric string")
 	}
 	EthAddress := string(args[2])
 
 	// Get the existing person
 	personAsBytes, err := stub.GetState(ID)
 	if err != nil {
-		return shim.Error("Failed to get person: " + err.Error())
+		return errcode.Internal("Failed to get person: " + err.Error())
 	} else if personAsBytes == nil {
-		return shim.Error("Person not found")
+		return errcode.NotFound("Person not found")
 	}
 
 	var person person
 	err = json.Unmarshal(personAsBytes, &person)
 	if err != nil {
-		return shim.Error("Failed to unmarshal person: " + err.Error())
+		return errcode.Internal("Failed to unmarshal person: " + err.Error())
 	}
 
 	// Update only the Age and EthAddress fields
@@ -288,23 +277,14 @@ func (t *DatabaseChaincode) updatePerson(stub shim.ChaincodeStubInterface, args
 	// Marshal the updated person object to JSON
 	personJSONasBytes, err := json.Marshal(person)
 	if err != nil {
-		return shim.Error(err.Error())
+		return errcode.FromError(err)
 	}
 
 	// Save the updated person back to the ledger
 	err = stub.PutState(ID, personJSONasBytes)
 	if err != nil {
-		return shim.Error(err.Error())
+		return errcode.FromError(err)
 	}
 
 	return shim.Success(nil)
 }
-
-
-func main() {
-	err := shim.Start(new(DatabaseChaincode))
-	if err != nil {
-		fmt.Printf("Error starting Simple chaincode: %s", err)
-	}
-}
-
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/chaincode/lib/export"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ExportPersons returns every person record as JSON Lines, in ID order
+func (t *DatabaseChaincode) ExportPersons(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) > 2 {
+		return errcode.InvalidArgument("Incorrect number of arguments. Expecting 0 to 2: pageSize, bookmark")
+	}
+	page, err := export.PageArgs(args)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	resultsIterator, err := stub.GetStateByRange("", "")
+	if err != nil {
+		return errcode.Internal(fmt.Sprintf("Failed to query persons: %s", err))
+	}
+	err = page.Iterate(resultsIterator, func(key string, value []byte) (interface{}, error) {
+		var p person
+		err := json.Unmarshal(value, &p)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal person %s: %s", key, err)
+		}
+		return &p, nil
+	})
+	if err != nil {
+		return errcode.FromError(err)
+	}
+
+	pageBytes, err := page.Bytes(stub)
+	if err != nil {
+		return errcode.FromError(err)
+	}
+	return shim.Success(pageBytes)
+}
@@ -0,0 +1,15 @@
+package database
+
+import (
+	"github.com/chaincode/lib/migrate"
+)
+
+// Schema version of person records, the only objects this chaincode stores
+const (
+	personVersion = 1
+)
+
+// collections registers the upgrade functions for every stored object type
+var collections = []migrate.Collection{
+	{Name: "person", StartKey: "", EndKey: "", Upgrades: []migrate.Upgrade{migrate.Stamp}},
+}
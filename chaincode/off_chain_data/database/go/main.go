@@ -0,0 +1,15 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/chaincode/lib/launch"
+	"github.com/chaincode/off_chain_data/database/go/database"
+)
+
+func main() {
+	err := launch.Start(new(database.DatabaseChaincode))
+	if err != nil {
+		fmt.Printf("Error starting DatabaseChaincode: %s", err)
+	}
+}
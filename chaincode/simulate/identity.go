@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/msp"
+)
+
+// attrsOID is the certificate extension Fabric CA stores ecert attributes in,
+// read by the cid library
+var attrsOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// Identity is a user of one of the network's organisations
+type Identity struct {
+	Name    string
+	MSPID   string
+	Attrs   map[string]string
+	Creator []byte
+}
+
+// Account returns the account ID the token and multisign chaincodes use for the identity
+func (id *Identity) Account() string {
+	return hex.EncodeToString(id.Creator)
+}
+
+// org is a simulated organisation CA
+type org struct {
+	name   string
+	domain string
+	key    *ecdsa.PrivateKey
+	cert   *x509.Certificate
+	serial int64
+}
+
+// Identities issues certificates shaped like cryptogen's, with Fabric CA
+// attributes added where a scenario asks for them
+type Identities struct {
+	orgs       map[string]*org
+	identities map[string]*Identity
+}
+
+// NewIdentities returns an empty identity registry
+func NewIdentities() *Identities {
+	return &Identities{orgs: make(map[string]*org), identities: make(map[string]*Identity)}
+}
+
+// Declare issues the identity User@Org with the given attributes. It fails if
+// the identity was already issued with different attributes
+func (ids *Identities) Declare(name string, attrs map[string]string) (*Identity, error) {
+	if id, exists := ids.identities[name]; exists {
+		if len(attrs) > 0 && fmt.Sprint(attrs) != fmt.Sprint(id.Attrs) {
+			return nil, fmt.Errorf("Identity %s was already issued with attributes %v", name, id.Attrs)
+		}
+		return id, nil
+	}
+
+	parts := strings.SplitN(name, "@", 2)
+	if len(parts) != 2 || parts[0] == "" || !strings.HasPrefix(parts[1], "Org") {
+		return nil, fmt.Errorf("Invalid identity %q. Expecting User@Org, e.g. User8@OrgStaff", name)
+	}
+	o, err := ids.org(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	creator, err := o.issue(parts[0], attrs)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to issue %s: %s", name, err)
+	}
+	id := &Identity{Name: name, MSPID: o.name + "MSP", Attrs: attrs, Creator: creator}
+	ids.identities[name] = id
+	return id, nil
+}
+
+// Get returns the identity, issuing it without attributes on first use
+func (ids *Identities) Get(name string) (*Identity, error) {
+	return ids.Declare(name, nil)
+}
+
+// org returns the CA of the named organisation, creating it on first use
+func (ids *Identities) org(name string) (*org, error) {
+	if o, exists := ids.orgs[name]; exists {
+		return o, nil
+	}
+	// OrgStaff is served from orgStaff.example.com, as in first-network
+	domain := strings.ToLower(name[:1]) + name[1:] + ".example.com"
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca." + domain, Organization: []string{domain}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	o := &org{name: name, domain: domain, key: key, cert: cert, serial: 1}
+	ids.orgs[name] = o
+	return o, nil
+}
+
+// issue creates a client certificate and returns the serialized identity
+// the peer would pass to chaincode as the transaction creator
+func (o *org) issue(user string, attrs map[string]string) ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	o.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(o.serial),
+		Subject: pkix.Name{
+			CommonName:         user + "@" + o.domain,
+			OrganizationalUnit: []string{"client"},
+			Country:            []string{"US"},
+			Province:           []string{"California"},
+			Locality:           []string{"San Francisco"},
+		},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:  x509.KeyUsageDigitalSignature,
+	}
+	if len(attrs) > 0 {
+		value, err := json.Marshal(map[string]map[string]string{"attrs": attrs})
+		if err != nil {
+			return nil, err
+		}
+		template.ExtraExtensions = []pkix.Extension{{Id: attrsOID, Value: value}}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, o.cert, &key.PublicKey, o.key)
+	if err != nil {
+		return nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return proto.Marshal(&msp.SerializedIdentity{Mspid: o.name + "MSP", IdBytes: certPEM})
+}
@@ -0,0 +1,45 @@
+// Command simulate runs business-flow scenarios against the token_erc20,
+// multisign and database chaincodes in one process, without a Fabric network.
+//
+// Chaincodes run on mock stubs with per-org identities issued on the fly
+// (User8@OrgStaff, User1@OrgAccountant, Admin@OrgManager, ...), optional
+// Fabric CA attributes, cross-chaincode routing and a simulated clock.
+// From the chaincode folder in the GOPATH:
+//
+//	go run ./simulate simulate/scenarios/*.json
+//
+// go test ./simulate runs every scenario in simulate/scenarios.
+//
+// See simulate/scenarios for the JSON scenario format.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	verbose := flag.Bool("v", false, "print every transaction's response")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: simulate [-v] scenario.json...")
+		os.Exit(2)
+	}
+
+	failed := 0
+	for _, path := range flag.Args() {
+		scenario, err := LoadScenario(path)
+		if err == nil {
+			err = scenario.Run(os.Stdout, *verbose)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d scenarios failed\n", failed, flag.NArg())
+		os.Exit(1)
+	}
+}
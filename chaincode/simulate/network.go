@@ -0,0 +1,279 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/common"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/hyperledger/fabric/protos/utils"
+)
+
+// instance is a chaincode instantiated on one channel, with its own world state
+type instance struct {
+	name    string
+	channel string
+	cc      shim.Chaincode
+	stub    *shim.MockStub
+}
+
+// Network runs chaincode instances in process. Each transaction sees the
+// invoking identity, transient data, signed proposal and a simulated clock,
+// and its writes are discarded if it fails, as they would be on a peer
+type Network struct {
+	instances map[string]*instance
+	clock     time.Time
+	txCount   int
+}
+
+// NewNetwork returns a network with no chaincodes, its clock set to start
+func NewNetwork(start time.Time) *Network {
+	return &Network{instances: make(map[string]*instance), clock: start}
+}
+
+func instanceKey(name, channel string) string {
+	return name + "/" + channel
+}
+
+// Deploy instantiates cc as chaincode name on channel and runs its Init
+func (n *Network) Deploy(name, channel string, cc shim.Chaincode) error {
+	key := instanceKey(name, channel)
+	if _, exists := n.instances[key]; exists {
+		return fmt.Errorf("Chaincode %s is already deployed on %s", name, channel)
+	}
+	stub := shim.NewMockStub(name, cc)
+	stub.ChannelID = channel
+	inst := &instance{name: name, channel: channel, cc: cc, stub: stub}
+	n.instances[key] = inst
+
+	stub.MockTransactionStart("init")
+	response := cc.Init(stub)
+	stub.MockTransactionEnd("init")
+	if response.Status >= shim.ERRORTHRESHOLD {
+		return fmt.Errorf("Init of %s failed: %s", name, response.Message)
+	}
+	return nil
+}
+
+// Channels returns the channels chaincode name is deployed on
+func (n *Network) Channels(name string) []string {
+	var channels []string
+	for _, inst := range n.instances {
+		if inst.name == name {
+			channels = append(channels, inst.channel)
+		}
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// Advance moves the simulated clock forward
+func (n *Network) Advance(d time.Duration) {
+	n.clock = n.clock.Add(d)
+}
+
+// Event is the chaincode event a transaction emitted
+type Event struct {
+	Name    string
+	Payload []byte
+}
+
+// Result is the outcome of a transaction
+type Result struct {
+	TxID     string
+	Response pb.Response
+	Event    *Event
+}
+
+// transaction holds what every stub taking part in one transaction shares
+type transaction struct {
+	id        string
+	top       *instance
+	creator   []byte
+	transient map[string][]byte
+	proposal  *pb.SignedProposal
+	timestamp *timestamp.Timestamp
+	event     *Event
+	snapshots map[*instance]map[string][]byte
+}
+
+// Invoke submits a transaction calling function on chaincode name on channel as id
+func (n *Network) Invoke(id *Identity, name, channel string, args []string, transient map[string][]byte) (*Result, error) {
+	inst, exists := n.instances[instanceKey(name, channel)]
+	if !exists {
+		return nil, fmt.Errorf("Chaincode %s is not deployed on %s", name, channel)
+	}
+
+	input := make([][]byte, len(args))
+	for i, arg := range args {
+		input[i] = []byte(arg)
+	}
+	cis := &pb.ChaincodeInvocationSpec{ChaincodeSpec: &pb.ChaincodeSpec{
+		Type:        pb.ChaincodeSpec_GOLANG,
+		ChaincodeId: &pb.ChaincodeID{Name: name},
+		Input:       &pb.ChaincodeInput{Args: input},
+	}}
+	proposal, _, err := utils.CreateProposalFromCIS(common.HeaderType_ENDORSER_TRANSACTION, channel, cis, id.Creator)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create proposal: %s", err)
+	}
+	proposalBytes, err := proto.Marshal(proposal)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal proposal: %s", err)
+	}
+
+	// Every transaction gets its own second so timestamps are strictly increasing
+	n.txCount++
+	n.clock = n.clock.Add(time.Second)
+	txHash := sha256.Sum256([]byte(strconv.Itoa(n.txCount)))
+	tx := &transaction{
+		id:        hex.EncodeToString(txHash[:]),
+		top:       inst,
+		creator:   id.Creator,
+		transient: transient,
+		proposal:  &pb.SignedProposal{ProposalBytes: proposalBytes},
+		timestamp: &timestamp.Timestamp{Seconds: n.clock.Unix()},
+		snapshots: make(map[*instance]map[string][]byte),
+	}
+
+	response := n.call(tx, inst, input)
+	if response.Status >= shim.ERRORTHRESHOLD {
+		// A failed transaction is never committed
+		for touched, state := range tx.snapshots {
+			touched.restore(state)
+		}
+		tx.event = nil
+	}
+	return &Result{TxID: tx.id, Response: response, Event: tx.event}, nil
+}
+
+// call runs one chaincode invocation within tx, recovering from panics the way
+// the peer turns a crashed chaincode into a failed proposal
+func (n *Network) call(tx *transaction, inst *instance, args [][]byte) (response pb.Response) {
+	if _, exists := tx.snapshots[inst]; !exists {
+		tx.snapshots[inst] = inst.snapshot()
+	}
+	stub := &txStub{MockStub: inst.stub, network: n, instance: inst, tx: tx, args: args}
+
+	inst.stub.MockTransactionStart(tx.id)
+	defer inst.stub.MockTransactionEnd(tx.id)
+	defer func() {
+		if r := recover(); r != nil {
+			response = shim.Error(fmt.Sprintf("Chaincode %s panicked: %v", inst.name, r))
+		}
+	}()
+	return inst.cc.Invoke(stub)
+}
+
+// snapshot copies the instance's world state
+func (inst *instance) snapshot() map[string][]byte {
+	state := make(map[string][]byte, len(inst.stub.State))
+	for key, value := range inst.stub.State {
+		state[key] = value
+	}
+	return state
+}
+
+// restore replaces the world state, rebuilding the sorted key list range queries use
+func (inst *instance) restore(state map[string][]byte) {
+	keys := make([]string, 0, len(state))
+	for key := range state {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	inst.stub.State = state
+	inst.stub.Keys = list.New()
+	for _, key := range keys {
+		inst.stub.Keys.PushBack(key)
+	}
+}
+
+// txStub is the stub a chaincode sees during a simulated transaction. State
+// access goes to the instance's MockStub; everything the MockStub cannot
+// provide comes from the transaction
+type txStub struct {
+	*shim.MockStub
+	network  *Network
+	instance *instance
+	tx       *transaction
+	args     [][]byte
+}
+
+func (s *txStub) GetArgs() [][]byte {
+	return s.args
+}
+
+func (s *txStub) GetStringArgs() []string {
+	strargs := make([]string, len(s.args))
+	for i, arg := range s.args {
+		strargs[i] = string(arg)
+	}
+	return strargs
+}
+
+func (s *txStub) GetFunctionAndParameters() (string, []string) {
+	allargs := s.GetStringArgs()
+	if len(allargs) == 0 {
+		return "", []string{}
+	}
+	return allargs[0], allargs[1:]
+}
+
+func (s *txStub) GetCreator() ([]byte, error) {
+	return s.tx.creator, nil
+}
+
+func (s *txStub) GetTransient() (map[string][]byte, error) {
+	return s.tx.transient, nil
+}
+
+func (s *txStub) GetSignedProposal() (*pb.SignedProposal, error) {
+	return s.tx.proposal, nil
+}
+
+func (s *txStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return s.tx.timestamp, nil
+}
+
+// SetEvent keeps the last event set by the top-level chaincode, as the peer does
+func (s *txStub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return fmt.Errorf("Event name can not be nil string.")
+	}
+	if s.instance == s.tx.top {
+		s.tx.event = &Event{Name: name, Payload: payload}
+	}
+	return nil
+}
+
+// InvokeChaincode routes to another instance within the same transaction.
+// Writes made on another channel are discarded, since the peer only commits
+// the calling channel's write set
+func (s *txStub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) pb.Response {
+	if channel == "" {
+		channel = s.instance.channel
+	}
+	target, exists := s.network.instances[instanceKey(chaincodeName, channel)]
+	if !exists {
+		return shim.Error(fmt.Sprintf("Chaincode %s is not deployed on %s", chaincodeName, channel))
+	}
+	if target == s.instance {
+		return shim.Error("Chaincode cannot invoke itself")
+	}
+	if channel == s.instance.channel {
+		return s.network.call(s.tx, target, args)
+	}
+
+	state := target.snapshot()
+	response := s.network.call(s.tx, target, args)
+	target.restore(state)
+	return response
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chaincode/lib/errcode"
+	"github.com/chaincode/local_token/mytoken/go/token"
+	"github.com/chaincode/multisign/multisign"
+	"github.com/chaincode/off_chain_data/database/go/database"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// contracts are the chaincodes a scenario can deploy, by type
+var contracts = map[string]func() shim.Chaincode{
+	"token":     func() shim.Chaincode { return new(token.TokenERC20Chaincode) },
+	"multisign": func() shim.Chaincode { return new(multisign.MultisignChaincode) },
+	"database":  func() shim.Chaincode { return new(database.DatabaseChaincode) },
+}
+
+// defaultStart is the simulated time of a scenario's first transaction
+const defaultStart = "2024-01-01T00:00:00Z"
+
+// Scenario is a business flow to run against freshly deployed chaincodes
+type Scenario struct {
+	Name       string                       `json:"name"`
+	Start      string                       `json:"start"`
+	Chaincodes []Deployment                 `json:"chaincodes"`
+	Identities map[string]map[string]string `json:"identities"`
+	Steps      []Step                       `json:"steps"`
+}
+
+// Deployment instantiates a contract type under a chaincode name on a channel
+type Deployment struct {
+	Name     string `json:"name"`
+	Contract string `json:"contract"`
+	Channel  string `json:"channel"`
+}
+
+// Step is either a transaction, as "as" invoking "function" on "chaincode",
+// or a clock advance. Arguments and transient values may reference
+// ${User@Org} for the identity's account ID and ${name} for a saved payload
+type Step struct {
+	Name      string            `json:"name"`
+	As        string            `json:"as"`
+	Chaincode string            `json:"chaincode"`
+	Channel   string            `json:"channel"`
+	Function  string            `json:"function"`
+	Args      []string          `json:"args"`
+	Transient map[string]string `json:"transient"`
+	Save      string            `json:"save"`
+	Advance   string            `json:"advance"`
+	Expect    *Expect           `json:"expect"`
+}
+
+// Expect describes the outcome a step must have. Without an expectation the
+// step must succeed; with Error set it must fail with a message containing it
+// and with Code set it must fail with that error code
+type Expect struct {
+	Status   int32   `json:"status"`
+	Payload  *string `json:"payload"`
+	Contains string  `json:"contains"`
+	Error    string  `json:"error"`
+	Code     string  `json:"code"`
+	Event    string  `json:"event"`
+}
+
+// LoadScenario reads a JSON scenario file
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var scenario Scenario
+	err = json.Unmarshal(data, &scenario)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid scenario %s: %s", path, err)
+	}
+	if scenario.Name == "" {
+		scenario.Name = path
+	}
+	return &scenario, nil
+}
+
+// runner holds the state of one scenario run
+type runner struct {
+	network    *Network
+	identities *Identities
+	vars       map[string]string
+	out        io.Writer
+	verbose    bool
+}
+
+var placeholder = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// expand replaces ${User@Org} with the identity's account ID and ${name} with a saved value
+func (r *runner) expand(s string) (string, error) {
+	var expandErr error
+	expanded := placeholder.ReplaceAllStringFunc(s, func(match string) string {
+		name := match[2 : len(match)-1]
+		if strings.Contains(name, "@") {
+			id, err := r.identities.Get(name)
+			if err != nil {
+				expandErr = err
+				return match
+			}
+			return id.Account()
+		}
+		value, exists := r.vars[name]
+		if !exists {
+			expandErr = fmt.Errorf("Unknown variable %s", name)
+			return match
+		}
+		return value
+	})
+	return expanded, expandErr
+}
+
+// Run deploys the scenario's chaincodes and runs its steps until one fails
+func (s *Scenario) Run(out io.Writer, verbose bool) error {
+	start := s.Start
+	if start == "" {
+		start = defaultStart
+	}
+	startTime, err := time.Parse(time.RFC3339, start)
+	if err != nil {
+		return fmt.Errorf("Invalid start time: %s", err)
+	}
+
+	r := &runner{network: NewNetwork(startTime), identities: NewIdentities(), vars: make(map[string]string), out: out, verbose: verbose}
+	for _, d := range s.Chaincodes {
+		newContract, exists := contracts[d.Contract]
+		if !exists {
+			return fmt.Errorf("Unknown contract %q. Expecting token, multisign or database", d.Contract)
+		}
+		err = r.network.Deploy(d.Name, d.Channel, newContract())
+		if err != nil {
+			return err
+		}
+	}
+	for name, attrs := range s.Identities {
+		_, err = r.identities.Declare(name, attrs)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(out, "scenario: %s\n", s.Name)
+	for i, step := range s.Steps {
+		label := step.describe(i + 1)
+		err = r.step(step)
+		if err != nil {
+			fmt.Fprintf(out, "  FAIL  %s: %s\n", label, err)
+			return fmt.Errorf("%s failed at step %d", s.Name, i+1)
+		}
+		fmt.Fprintf(out, "  PASS  %s\n", label)
+	}
+	return nil
+}
+
+func (step Step) describe(n int) string {
+	if step.Name != "" {
+		return fmt.Sprintf("%d %s", n, step.Name)
+	}
+	if step.Advance != "" {
+		return fmt.Sprintf("%d advance %s", n, step.Advance)
+	}
+	return fmt.Sprintf("%d %s %s.%s", n, step.As, step.Chaincode, step.Function)
+}
+
+func (r *runner) step(step Step) error {
+	if step.Advance != "" {
+		d, err := time.ParseDuration(step.Advance)
+		if err != nil {
+			return fmt.Errorf("Invalid advance: %s", err)
+		}
+		r.network.Advance(d)
+		return nil
+	}
+
+	id, err := r.identities.Get(step.As)
+	if err != nil {
+		return err
+	}
+	channel := step.Channel
+	if channel == "" {
+		channels := r.network.Channels(step.Chaincode)
+		if len(channels) != 1 {
+			return fmt.Errorf("Chaincode %s is deployed on %d channels; set channel", step.Chaincode, len(channels))
+		}
+		channel = channels[0]
+	}
+
+	args := []string{step.Function}
+	for _, arg := range step.Args {
+		expanded, err := r.expand(arg)
+		if err != nil {
+			return err
+		}
+		args = append(args, expanded)
+	}
+	var transient map[string][]byte
+	if len(step.Transient) > 0 {
+		transient = make(map[string][]byte)
+		for key, value := range step.Transient {
+			expanded, err := r.expand(value)
+			if err != nil {
+				return err
+			}
+			transient[key] = []byte(expanded)
+		}
+	}
+
+	result, err := r.network.Invoke(id, step.Chaincode, channel, args, transient)
+	if err != nil {
+		return err
+	}
+	response := result.Response
+	if r.verbose {
+		fmt.Fprintf(r.out, "        tx %s status %d message %q payload %s\n", result.TxID[:8], response.Status, response.Message, response.Payload)
+	}
+	if step.Save != "" {
+		r.vars[step.Save] = string(response.Payload)
+	}
+	return step.check(result, r)
+}
+
+// check compares a transaction's result with the step's expectation
+func (step Step) check(result *Result, r *runner) error {
+	response := result.Response
+	expect := step.Expect
+	if expect == nil {
+		expect = &Expect{}
+	}
+
+	if expect.Code != "" {
+		if response.Status < shim.ERRORTHRESHOLD {
+			return fmt.Errorf("expected failure with code %s, got status %d", expect.Code, response.Status)
+		}
+		if code := errcode.Parse(response).Code; string(code) != expect.Code {
+			return fmt.Errorf("expected failure with code %s, got %s: %s", expect.Code, code, response.Message)
+		}
+	}
+	if expect.Error != "" {
+		if response.Status < shim.ERRORTHRESHOLD {
+			return fmt.Errorf("expected failure containing %q, got status %d", expect.Error, response.Status)
+		}
+		if !strings.Contains(response.Message, expect.Error) {
+			return fmt.Errorf("expected failure containing %q, got %q", expect.Error, response.Message)
+		}
+	} else if expect.Status != 0 {
+		if response.Status != expect.Status {
+			return fmt.Errorf("expected status %d, got %d: %s", expect.Status, response.Status, response.Message)
+		}
+	} else if expect.Code == "" && response.Status >= shim.ERRORTHRESHOLD {
+		return fmt.Errorf("unexpected failure: %s", response.Message)
+	}
+
+	if expect.Payload != nil {
+		want, err := r.expand(*expect.Payload)
+		if err != nil {
+			return err
+		}
+		if string(response.Payload) != want {
+			return fmt.Errorf("expected payload %q, got %q", want, response.Payload)
+		}
+	}
+	if expect.Contains != "" {
+		want, err := r.expand(expect.Contains)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(response.Payload), want) {
+			return fmt.Errorf("expected payload containing %q, got %q", want, response.Payload)
+		}
+	}
+	if expect.Event != "" {
+		if result.Event == nil {
+			return fmt.Errorf("expected event %s, got none", expect.Event)
+		}
+		if result.Event.Name != expect.Event {
+			return fmt.Errorf("expected event %s, got %s", expect.Event, result.Event.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+// TestScenarios runs every scenario in scenarios, so a chaincode change that
+// breaks a business flow fails go test
+func TestScenarios(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("scenarios", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no scenarios found")
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			scenario, err := LoadScenario(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			err = scenario.Run(&out, testing.Verbose())
+			if err != nil {
+				t.Errorf("%s\n%s", err, out.String())
+			}
+		})
+	}
+}
@@ -14,6 +14,34 @@ const port = 3000;
 app.use(cors()); // Thêm dòng này
 app.use(bodyParser.json());
 
+// HTTP status for each chaincode error code, see chaincode/lib/errcode
+const errorStatuses = {
+    INVALID_ARGUMENT: 400,
+    FORBIDDEN: 403,
+    NOT_FOUND: 404,
+    CONFLICT: 409,
+    INSUFFICIENT_FUNDS: 422,
+    INTERNAL: 500,
+};
+
+// Extract the {code, message, details} envelope a chaincode failed with, or null
+function chaincodeError(error) {
+    const messages = (error.endorsements || []).map((e) => e.message).concat(error.message);
+    for (const message of messages) {
+        const start = message ? message.indexOf('{"code"') : -1;
+        if (start < 0) {
+            continue;
+        }
+        try {
+            const envelope = JSON.parse(message.substring(start, message.lastIndexOf('}') + 1));
+            return { status: errorStatuses[envelope.code] || 500, body: { error: envelope.message, code: envelope.code, details: envelope.details } };
+        } catch (e) {
+            // Not an envelope, keep looking
+        }
+    }
+    return null;
+}
+
 app.post('/enroll-admin', async (req, res) => {
     const orgName = req.body.orgName;
     
@@ -140,6 +168,10 @@ app.post('/input-info', async (req, res) => {
         res.status(200).json({ message: 'Transaction has been submitted successfully' });
     } catch (error) {
         console.error(`Failed to submit transaction: ${error}`);
+        const failure = chaincodeError(error);
+        if (failure) {
+            return res.status(failure.status).json(failure.body);
+        }
         res.status(500).json({ error: `Failed to submit transaction: ${error.message}` });
     }
 });
@@ -179,6 +211,10 @@ app.post('/update-info', async (req, res) => {
         res.status(200).json({ message: 'Transaction has been submitted successfully' });
     } catch (error) {
         console.error(`Failed to submit transaction: ${error}`);
+        const failure = chaincodeError(error);
+        if (failure) {
+            return res.status(failure.status).json(failure.body);
+        }
         res.status(500).json({ error: `Failed to submit transaction: ${error.message}` });
     }
 });
@@ -214,6 +250,10 @@ app.post('/query-user', async (req, res) => {
         res.status(200).json({ result: result.toString() });
     } catch (error) {
         console.error(`Failed to submit transaction: ${error}`);
+        const failure = chaincodeError(error);
+        if (failure) {
+            return res.status(failure.status).json(failure.body);
+        }
         res.status(500).json({ error: `Failed to submit transaction: ${error.message}` });
     }
 });